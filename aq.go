@@ -0,0 +1,324 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Queue payload types.
+const (
+	// PayloadRAW is the RAW payload, at most 16383 bytes.
+	PayloadRAW = "RAW"
+	// PayloadJSON is the JSON payload (21c+), at most 32767 bytes serialized.
+	PayloadJSON = "JSON"
+)
+
+// Visibility determines whether the enqueue or dequeue is part of the
+// current transaction.
+type Visibility int
+
+const (
+	// VisibleOnCommit makes the enqueue or dequeue part of the current transaction.
+	VisibleOnCommit = Visibility(2) // DBMS_AQ.ON_COMMIT
+	// VisibleImmediate makes the enqueue or dequeue an autonomous transaction.
+	VisibleImmediate = Visibility(1) // DBMS_AQ.IMMEDIATE
+)
+
+// Dequeue waits.
+const (
+	// WaitForever makes Dequeue wait till a message arrives.
+	WaitForever = time.Duration(-1)
+	// NoWait makes Dequeue return immediately if there is no message.
+	NoWait = time.Duration(0)
+)
+
+// maxQueuePayload is the size of the PL/SQL VARCHAR2 buffers of the payloads.
+const maxQueuePayload = 32767
+
+// Message is a message of an Oracle Advanced Queue.
+type Message struct {
+	// Payload is the RAW payload, or the JSON text.
+	Payload []byte
+
+	// Correlation is the identifier of the message, to be used by Dequeue.
+	Correlation string
+
+	// Delay is the time the message is not available for dequeue after enqueue.
+	Delay time.Duration
+
+	// Expiration is the time the message is available for dequeue,
+	// after the Delay; zero means never expire.
+	Expiration time.Duration
+
+	// Priority of the message: the smaller, the higher;
+	// zero means the DBMS_AQ default, 1.
+	Priority int
+
+	// MsgID is the identifier of the message, set by Enqueue and Dequeue.
+	MsgID []byte
+
+	// Attempts is the number of dequeue attempts of the message, set by Dequeue.
+	Attempts int
+}
+
+// EnqOptions are the options of Queue.Enqueue.
+type EnqOptions struct {
+	// Visibility defaults to VisibleOnCommit.
+	Visibility Visibility
+}
+
+// DeqOptions are the options of Queue.Dequeue.
+type DeqOptions struct {
+	// Visibility defaults to VisibleOnCommit.
+	Visibility Visibility
+
+	// Wait is the time to wait for the first message: NoWait, WaitForever,
+	// or rounded up to whole seconds.
+	Wait time.Duration
+
+	// Consumer is the name of the consumer, for multi-consumer queues.
+	Consumer string
+
+	// Correlation selects the messages with the correlation identifier
+	// (which may contain the % and _ wildcards).
+	Correlation string
+}
+
+// Queue is an Oracle Advanced Queue, to be used through the session it has
+// been created with.
+type Queue struct {
+	ses         *Ses
+	name        string
+	payloadType string
+
+	EnqOptions EnqOptions
+	DeqOptions DeqOptions
+}
+
+// Queue returns the queue with the given name and payload type
+// (PayloadRAW or PayloadJSON).
+//
+// Object type payloads are not supported, as they cannot be bound.
+func (ses *Ses) Queue(name, payloadType string) (*Queue, error) {
+	if err := ses.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	if name == "" {
+		return nil, errNew("queue name must not be empty")
+	}
+	payloadType = strings.ToUpper(payloadType)
+	switch payloadType {
+	case PayloadRAW, PayloadJSON:
+	default:
+		return nil, errF("queue payload type %q is not supported, only %s and %s", payloadType, PayloadRAW, PayloadJSON)
+	}
+	return &Queue{ses: ses, name: name, payloadType: payloadType}, nil
+}
+
+// Name returns the name of the queue.
+func (q *Queue) Name() string { return q.name }
+
+// PayloadType returns the payload type of the queue.
+func (q *Queue) PayloadType() string { return q.payloadType }
+
+// Enqueue enqueues the messages, in one round-trip, and sets their MsgID.
+func (q *Queue) Enqueue(msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	n := len(msgs)
+	payloads, correlations := make([]string, n), make([]string, n)
+	delays, expirations, priorities := make([]int64, n), make([]int64, n), make([]int64, n)
+	for i, m := range msgs {
+		if m.Payload == nil {
+			return errF("payload of message %d is nil", i)
+		}
+		if q.payloadType == PayloadRAW {
+			payloads[i] = hex.EncodeToString(m.Payload)
+		} else {
+			payloads[i] = string(m.Payload)
+		}
+		if len(payloads[i]) > maxQueuePayload {
+			return errF("payload of message %d is too long (%d)", i, len(m.Payload))
+		}
+		correlations[i] = m.Correlation
+		delays[i] = int64(roundSeconds(m.Delay))
+		expirations[i] = -1 // DBMS_AQ.NEVER
+		if m.Expiration > 0 {
+			expirations[i] = int64(roundSeconds(m.Expiration))
+		}
+		priorities[i] = 1 // the DBMS_AQ default
+		if m.Priority != 0 {
+			priorities[i] = int64(m.Priority)
+		}
+	}
+	payload := "HEXTORAW(:8(i))"
+	if q.payloadType == PayloadJSON {
+		payload = "JSON(:8(i))"
+	}
+	qry := `DECLARE
+  v_enq DBMS_AQ.ENQUEUE_OPTIONS_T;
+  v_props DBMS_AQ.MESSAGE_PROPERTIES_T;
+  v_empty DBMS_AQ.MESSAGE_PROPERTIES_T;
+  v_msgid RAW(16);
+BEGIN
+  v_enq.visibility := :1;
+  FOR i IN 1..:2 LOOP
+    v_props := v_empty;
+    v_props.correlation := :3(i);
+    v_props.delay := :4(i);
+    v_props.expiration := :5(i);
+    v_props.priority := :6(i);
+    DBMS_AQ.ENQUEUE(queue_name => :7, enqueue_options => v_enq,
+      message_properties => v_props, payload => ` + payload + `, msgid => v_msgid);
+    :9(i) := RAWTOHEX(v_msgid);
+  END LOOP;
+END;`
+	msgIDs := make([]string, 0, n)
+	stmt, err := q.ses.Prep(qry)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	stmt.SetCfg(stmt.Cfg().SetStringPtrBufferSize(32))
+	if _, err = stmt.ExeP(
+		int64(visibility(q.EnqOptions.Visibility)), int64(n),
+		correlations, delays, expirations, priorities,
+		q.name, payloads, &msgIDs,
+	); err != nil {
+		return err
+	}
+	for i, id := range msgIDs {
+		if i < n {
+			msgs[i].MsgID, _ = hex.DecodeString(id)
+		}
+	}
+	return nil
+}
+
+// Dequeue dequeues at most max (at least 1) messages, in one round-trip.
+//
+// Only the first message is waited for, as DeqOptions.Wait says:
+// Dequeue returns the messages available at that time.
+// If there is no message till the wait ends, no message and no error is returned.
+//
+// When ctx is done, the dequeue is Broken, and ctx.Err() is returned.
+func (q *Queue) Dequeue(ctx context.Context, max int) ([]*Message, error) {
+	if max < 1 {
+		max = 1
+	}
+	opts := q.DeqOptions
+	wait := int64(-1) // DBMS_AQ.FOREVER
+	if opts.Wait >= 0 {
+		wait = int64(roundSeconds(opts.Wait))
+	}
+	payloadDecl, serialize := "RAW(32767)", "v_text := RAWTOHEX(v_payload);"
+	if q.payloadType == PayloadJSON {
+		payloadDecl = "JSON"
+		serialize = "SELECT JSON_SERIALIZE(v_payload RETURNING VARCHAR2(32767)) INTO v_text FROM DUAL;"
+	}
+	qry := `DECLARE
+  v_deq DBMS_AQ.DEQUEUE_OPTIONS_T;
+  v_props DBMS_AQ.MESSAGE_PROPERTIES_T;
+  v_msgid RAW(16);
+  v_payload ` + payloadDecl + `;
+  v_text VARCHAR2(32767);
+  v_n PLS_INTEGER := 0;
+  e_timeout EXCEPTION;
+  PRAGMA EXCEPTION_INIT(e_timeout, -25228);
+BEGIN
+  v_deq.visibility := :1;
+  v_deq.wait := :2;
+  v_deq.consumer_name := :3;
+  v_deq.correlation := :4;
+  FOR i IN 1..:5 LOOP
+    BEGIN
+      DBMS_AQ.DEQUEUE(queue_name => :6, dequeue_options => v_deq,
+        message_properties => v_props, payload => v_payload, msgid => v_msgid);
+    EXCEPTION WHEN e_timeout THEN EXIT;
+    END;
+    v_n := i;
+    ` + serialize + `
+    :7(i) := v_text;
+    :8(i) := v_props.correlation;
+    :9(i) := RAWTOHEX(v_msgid);
+    :10(i) := v_props.attempts;
+    v_deq.wait := DBMS_AQ.NO_WAIT;
+  END LOOP;
+  :11 := v_n;
+END;`
+	// The elements of a bound string array are as long as the longest of
+	// StringPtrBufferSize and the elements, so each array gets its own maximum.
+	payloads := outStrings(max, maxQueuePayload)
+	correlations := outStrings(max, 128)
+	msgIDs := make([]string, 0, max)
+	attempts := make([]int64, 0, max)
+	var n int64
+	stmt, err := q.ses.Prep(qry)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	stmt.SetCfg(stmt.Cfg().SetStringPtrBufferSize(2 * 16)) // RAWTOHEX of RAW(16)
+	if _, err = stmt.ExePContext(ctx,
+		int64(visibility(opts.Visibility)), wait, opts.Consumer, opts.Correlation,
+		int64(max), q.name,
+		&payloads, &correlations, &msgIDs, &attempts, &n,
+	); err != nil {
+		return nil, err
+	}
+	msgs := make([]*Message, 0, n)
+	for i := 0; i < int(n) && i < len(payloads); i++ {
+		m := &Message{Correlation: correlations[i]}
+		if q.payloadType == PayloadRAW {
+			if m.Payload, err = hex.DecodeString(payloads[i]); err != nil {
+				return msgs, errE(err)
+			}
+		} else {
+			m.Payload = []byte(payloads[i])
+		}
+		if i < len(msgIDs) {
+			m.MsgID, _ = hex.DecodeString(msgIDs[i])
+		}
+		if i < len(attempts) {
+			m.Attempts = int(attempts[i])
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// outStrings returns a PL/SQL OUT array for max elements of size bytes each.
+// Its only element is a placeholder of size bytes, overwritten by the block.
+func outStrings(max, size int) []string {
+	a := make([]string, 1, max)
+	a[0] = strings.Repeat(" ", size)
+	return a
+}
+
+// String returns the name and the payload type of the queue.
+func (q *Queue) String() string {
+	return fmt.Sprintf("%s(%s)", q.name, q.payloadType)
+}
+
+func visibility(v Visibility) Visibility {
+	if v == 0 {
+		return VisibleOnCommit
+	}
+	return v
+}
+
+// roundSeconds returns d in seconds, rounded up.
+func roundSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -213,8 +213,10 @@ func writeLob(ociLobLocator *C.OCILobLocator, stmt *Stmt, r io.Reader, lobBuffer
 		//fmt.Printf("r %v, current %v, buffer %v\n", r, current, buffer)
 		//fmt.Printf("C.OCI_NEED_DATA %v, C.OCI_SUCCESS %v\n", C.OCI_NEED_DATA, C.OCI_SUCCESS)
 		) == C.OCI_ERROR {
+			_stats.lobRW(0, 0)
 			return stmt.ses.srv.env.ociError()
 		}
+		_stats.lobRW(0, uint64(byteAmtp))
 		off += byteAmtp
 
 		if actPiece == C.OCI_LAST_PIECE || actPiece == C.OCI_ONE_PIECE {
@@ -0,0 +1,140 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <oci.h>
+
+// OCI_ATTR_CALL_TIMEOUT is available since 18c.
+#ifndef OCI_ATTR_CALL_TIMEOUT
+#define OCI_ATTR_CALL_TIMEOUT 531
+#endif
+
+static sword clientMajorVersion(void) {
+	sword major, minor, update, patch, port;
+	OCIClientVersion(&major, &minor, &update, &patch, &port);
+	return major;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// CallTimeoutError is returned when a round-trip to the server does not
+// finish within the time set with StmtCfg.SetCallTimeout.
+type CallTimeoutError struct {
+	// CallTimeout is the exceeded timeout.
+	CallTimeout time.Duration
+	// Err is the error returned by the interrupted OCI call.
+	Err error
+}
+
+func (e *CallTimeoutError) Error() string {
+	return fmt.Sprintf("call timeout (%s) exceeded: %v", e.CallTimeout, e.Err)
+}
+
+// Timeout returns true, to implement net.Error.
+func (e *CallTimeoutError) Timeout() bool { return true }
+
+// Temporary returns true, to implement net.Error.
+func (e *CallTimeoutError) Temporary() bool { return true }
+
+// Unwrap returns the error of the interrupted call.
+func (e *CallTimeoutError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrTimeout.
+func (e *CallTimeoutError) Is(target error) bool { return target == ErrTimeout }
+
+// IsCallTimeout reports whether err is, or wraps a *CallTimeoutError.
+func IsCallTimeout(err error) bool {
+	for ; err != nil; err = unwrap(err) {
+		if _, ok := err.(*CallTimeoutError); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	clientVersionOnce sync.Once
+	clientMajor       int
+)
+
+// hasCallTimeout reports whether the client library supports OCI_ATTR_CALL_TIMEOUT.
+func hasCallTimeout() bool {
+	clientVersionOnce.Do(func() { clientMajor = int(C.clientMajorVersion()) })
+	return clientMajor >= 18
+}
+
+// startCallTimeout limits the next round-trip(s) of the session to d.
+//
+// With 18c+ clients OCI_ATTR_CALL_TIMEOUT is set on the service context,
+// otherwise a watchdog Breaks the session when d passes.
+//
+// The returned function must be called with the error of the call:
+// it disarms the timeout, and returns a *CallTimeoutError if the call
+// has been interrupted by the timeout, err otherwise.
+func (ses *Ses) startCallTimeout(d time.Duration) func(err error) error {
+	if d <= 0 || ses == nil {
+		return func(err error) error { return err }
+	}
+	if hasCallTimeout() {
+		if ses.setCallTimeout(d) == nil {
+			return func(err error) error {
+				ses.setCallTimeout(0)
+				if errCode(err) == 3156 { // OCI call timed out
+					return &CallTimeoutError{CallTimeout: d, Err: err}
+				}
+				return err
+			}
+		}
+	}
+	// mu and done keep the watchdog from Breaking a later call of the session.
+	var (
+		mu    sync.Mutex
+		done  bool
+		fired bool
+	)
+	t := time.AfterFunc(d, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return
+		}
+		fired = true
+		ses.Break()
+	})
+	return func(err error) error {
+		t.Stop()
+		mu.Lock()
+		done = true
+		interrupted := fired
+		mu.Unlock()
+		if !interrupted || err == nil {
+			return err
+		}
+		return &CallTimeoutError{CallTimeout: d, Err: err}
+	}
+}
+
+// setCallTimeout sets OCI_ATTR_CALL_TIMEOUT (in milliseconds) on the service context.
+func (ses *Ses) setCallTimeout(d time.Duration) error {
+	ses.RLock()
+	defer ses.RUnlock()
+	if ses.ocisvcctx == nil {
+		return errF("Ses is closed")
+	}
+	ms := C.ub4(d / time.Millisecond)
+	if ms == 0 && d > 0 {
+		ms = 1
+	}
+	return ses.Env().setAttr(unsafe.Pointer(ses.ocisvcctx), C.OCI_HTYPE_SVCCTX,
+		unsafe.Pointer(&ms), 4, C.OCI_ATTR_CALL_TIMEOUT)
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <oci.h>
+*/
+import "C"
+
+import "strconv"
+
+// OracleType is the external data type code of an Oracle column or argument.
+type OracleType uint16
+
+// Oracle types.
+const (
+	TypeVarchar2     OracleType = C.SQLT_CHR
+	TypeNumber       OracleType = C.SQLT_NUM
+	TypeInteger      OracleType = C.SQLT_INT
+	TypeFloat        OracleType = C.SQLT_FLT
+	TypeString       OracleType = C.SQLT_STR
+	TypeVarnum       OracleType = C.SQLT_VNU
+	TypeLong         OracleType = C.SQLT_LNG
+	TypeVarchar      OracleType = C.SQLT_VCS
+	TypeDate         OracleType = C.SQLT_DAT
+	TypeVarraw       OracleType = C.SQLT_VBI
+	TypeNativeFloat  OracleType = C.SQLT_BFLOAT
+	TypeNativeDouble OracleType = C.SQLT_BDOUBLE
+	TypeRaw          OracleType = C.SQLT_BIN
+	TypeLongRaw      OracleType = C.SQLT_LBI
+	TypeUnsignedInt  OracleType = C.SQLT_UIN
+	TypeLongVarchar  OracleType = C.SQLT_LVC
+	TypeLongVarraw   OracleType = C.SQLT_LVB
+	TypeChar         OracleType = C.SQLT_AFC
+	TypeCharz        OracleType = C.SQLT_AVC
+	TypeBinaryFloat  OracleType = C.SQLT_IBFLOAT
+	TypeBinaryDouble OracleType = C.SQLT_IBDOUBLE
+	TypeRowid        OracleType = C.SQLT_RDD
+	TypeNamed        OracleType = C.SQLT_NTY
+	TypeRef          OracleType = C.SQLT_REF
+	TypeClob         OracleType = C.SQLT_CLOB
+	TypeBlob         OracleType = C.SQLT_BLOB
+	TypeBfile        OracleType = C.SQLT_FILE
+	TypeCursor       OracleType = C.SQLT_RSET
+	TypeOCIString    OracleType = C.SQLT_VST
+	TypeOCIDate      OracleType = C.SQLT_ODT
+	TypeANSIDate     OracleType = C.SQLT_DATE
+	TypeTimestamp    OracleType = C.SQLT_TIMESTAMP
+	TypeTimestampTZ  OracleType = C.SQLT_TIMESTAMP_TZ
+	TypeIntervalYM   OracleType = C.SQLT_INTERVAL_YM
+	TypeIntervalDS   OracleType = C.SQLT_INTERVAL_DS
+	TypeTimestampLTZ OracleType = C.SQLT_TIMESTAMP_LTZ
+)
+
+// Charset forms of Column.CharsetForm.
+const (
+	// CharsetFormImplicit is the database character set (CHAR, VARCHAR2, CLOB).
+	CharsetFormImplicit = C.SQLCS_IMPLICIT
+	// CharsetFormNChar is the national character set (NCHAR, NVARCHAR2, NCLOB).
+	CharsetFormNChar = C.SQLCS_NCHAR
+)
+
+// String returns the database type name, without the length, in uppercase.
+//
+// https://docs.oracle.com/cd/E11882_01/appdev.112/e10646/oci03typ.htm#LNOCI16271
+func (t OracleType) String() string {
+	switch t {
+	case TypeVarchar2:
+		return "VARCHAR2"
+	case TypeNumber:
+		return "NUMBER"
+	case TypeInteger:
+		return "INTEGER"
+	case TypeFloat:
+		return "FLOAT"
+	case TypeString:
+		return "STRING"
+	case TypeVarnum:
+		return "VARNUM"
+	case TypeLong:
+		return "LONG"
+	case TypeVarchar:
+		return "VARCHAR"
+	case TypeDate:
+		return "DATE"
+	case TypeVarraw:
+		return "VARRAW"
+	case TypeNativeFloat:
+		return "NATIVE FLOAT"
+	case TypeNativeDouble:
+		return "NATIVE DOUBLE"
+	case TypeRaw:
+		return "RAW"
+	case TypeLongRaw:
+		return "LONG RAW"
+	case TypeUnsignedInt:
+		return "UNSIGNED INT"
+	case TypeLongVarchar:
+		return "LONG VARCHAR"
+	case TypeLongVarraw:
+		return "LONG VARRAW"
+	case TypeChar:
+		return "CHAR"
+	case TypeCharz:
+		return "CHARZ"
+	case TypeBinaryFloat:
+		return "BINARY_FLOAT"
+	case TypeBinaryDouble:
+		return "BINARY_DOUBLE"
+	case TypeRowid:
+		return "ROWID"
+	case TypeNamed:
+		return "OBJECT"
+	case TypeRef:
+		return "REF"
+	case TypeClob:
+		return "CLOB"
+	case TypeBlob:
+		return "BLOB"
+	case TypeBfile:
+		return "BFILE"
+	case TypeCursor:
+		return "REF CURSOR"
+	case TypeOCIString:
+		return "OCI STRING"
+	case TypeOCIDate:
+		return "OCI DATE"
+	case TypeANSIDate:
+		return "ANSI DATE"
+	case TypeTimestamp:
+		return "TIMESTAMP"
+	case TypeTimestampTZ:
+		return "TIMESTAMP WITH TIME ZONE"
+	case TypeIntervalYM:
+		return "INTERVAL YEAR TO MONTH"
+	case TypeIntervalDS:
+		return "INTERVAL DAY TO SECOND"
+	case TypeTimestampLTZ:
+		return "TIMESTAMP WITH LOCAL TIME ZONE"
+	}
+	return strconv.Itoa(int(t))
+}
+
+// Column describes a select-list column of a result set,
+// or a column or argument described by Ses.Describe.
+type Column struct {
+	Name string
+	Type OracleType
+	// Length is the maximum length in bytes.
+	Length uint32
+	// CharLength is the maximum length in characters, and CharUsed
+	// reports whether it was declared in characters, as VARCHAR2(10 CHAR).
+	CharLength uint32
+	CharUsed   bool
+	// Precision and Scale are of NUMBER columns; Precision is 0 and
+	// Scale is -127 for NUMBER without precision.
+	Precision int16
+	Scale     int8
+	Nullable  bool
+	// CharsetID is the character set ID, and CharsetForm is
+	// CharsetFormImplicit or CharsetFormNChar, for character columns.
+	CharsetID, CharsetForm int
+	// Schema and TypeName are the schema and the name of the type
+	// of object columns.
+	Schema, TypeName string
+	// GoType is the Go type the values of the column are returned as;
+	// D for columns that are not fetched through a GoColumnType.
+	GoType GoColumnType
+}
@@ -182,17 +182,13 @@ func maybeBadConn(err error) error {
 		return nil
 	}
 	// database/sql API expect driver.ErrBadConn to reconnect to the database
-	if cd, ok := err.(interface {
-		Code() int
-	}); ok {
-		switch cd.Code() {
-		case 3113, 3114, 12528, 12545:
-			// ORA-03113: end-of-file on communication channel
-			// ORA-03114: not connected to ORACLE
-			// ORA-12528: TNS:listener: all appropriate instances are blocking new connections
-			// ORA-12545: Connect failed because target host or object does not exist
-			return driver.ErrBadConn
-		}
+	if isBadConnErr(err) {
+		return driver.ErrBadConn
 	}
 	return err
 }
+
+// isBadConnErr returns whether the error means that the connection is lost.
+func isBadConnErr(err error) bool {
+	return isErr(err, ErrConnectionLost)
+}
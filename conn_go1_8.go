@@ -68,6 +68,19 @@ func (con *Con) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx,
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	flags, err := txFlags(opts)
+	if err != nil {
+		return nil, err
+	}
+	con.log(_drv.Cfg().Log.Con.Begin)
+	if err := con.checkIsOpen(); err != nil {
+		return nil, err
+	}
+	return startTxContext(ctx, con.ses, flags)
+}
+
+// txFlags returns the OCITransStart flags for the options.
+func txFlags(opts driver.TxOptions) (uint32, error) {
 	var flags C.ub4
 	if opts.ReadOnly {
 		flags |= C.OCI_TRANS_READONLY
@@ -78,25 +91,26 @@ func (con *Con) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx,
 	case sql.LevelSerializable:
 		flags |= C.OCI_TRANS_SERIALIZABLE
 	default:
-		return nil, fmt.Errorf("Isolation level %v not supported.", level)
-	}
-	con.log(_drv.Cfg().Log.Con.Begin)
-	if err := con.checkIsOpen(); err != nil {
-		return nil, err
+		return 0, fmt.Errorf("Isolation level %v not supported.", level)
 	}
+	return uint32(flags), nil
+}
+
+// startTxContext starts a transaction on ses, breaking it if ctx is cancelled.
+func startTxContext(ctx context.Context, ses *Ses, flags uint32) (*Tx, error) {
 	var tx *Tx
 	done := make(chan error)
 	go func() {
 		defer close(done)
 		var err error
-		tx, err = con.ses.StartTx(TxFlags(uint32(flags)))
+		tx, err = ses.StartTx(TxFlags(flags))
 		done <- err
 	}()
 	var err error
 	select {
 	case <-ctx.Done():
 		if err = ctx.Err(); isCanceled(err) {
-			con.ses.Break()
+			ses.Break()
 		}
 	case err = <-done:
 		return tx, err
@@ -0,0 +1,550 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <stdlib.h>
+#include <oci.h>
+
+// rowError gets the i-th row error of an array DML executed with OCI_BATCH_ERRORS.
+static sword rowError(OCIError *errhp, OCIError **rowErrhp, ub4 i,
+		ub4 *rowOff, sb4 *code, char *buf, ub4 bufLen) {
+	sword r = OCIParamGet(errhp, OCI_HTYPE_ERROR, errhp, (void **)rowErrhp, i);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	r = OCIAttrGet(*rowErrhp, OCI_HTYPE_ERROR, rowOff, NULL, OCI_ATTR_DML_ROW_OFFSET, errhp);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	buf[0] = 0;
+	OCIErrorGet(*rowErrhp, 1, NULL, code, (OraText *)buf, bufLen, OCI_HTYPE_ERROR);
+	return OCI_SUCCESS;
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"gopkg.in/rana/ora.v4/date"
+)
+
+// RowSource is an iterator over the rows to be copied by CopyIn.
+type RowSource interface {
+	// Next advances to the next row, and reports whether there is one.
+	Next() bool
+	// Values returns the values of the current row, one for each column.
+	Values() ([]interface{}, error)
+	// Err returns the error which stopped the iteration.
+	Err() error
+}
+
+// CopyFromRows returns a RowSource over the rows.
+func CopyFromRows(rows [][]interface{}) RowSource {
+	return &rowsSource{rows: rows, i: -1}
+}
+
+type rowsSource struct {
+	rows [][]interface{}
+	i    int
+}
+
+func (r *rowsSource) Next() bool                     { r.i++; return r.i < len(r.rows) }
+func (r *rowsSource) Values() ([]interface{}, error) { return r.rows[r.i], nil }
+func (r *rowsSource) Err() error                     { return nil }
+
+// CopyOptions are the options of CopyIn.
+type CopyOptions struct {
+	// BatchSize is the number of rows bound in one array DML.
+	// The default 0 means an adaptive batch size, starting at 128,
+	// doubled while a batch takes less than 100ms, up to MaxBatchSize,
+	// and halved when a batch takes more than a second.
+	BatchSize int
+	// MaxBatchSize limits the adaptive batch size; the default is 8192.
+	MaxBatchSize int
+	// Keys are the key columns to upsert on with MERGE: the rows with
+	// matching keys are updated, the others inserted.
+	// Without Keys, the rows are inserted.
+	Keys []string
+	// MaxErrors stops the copy after so many row errors; 0 means no limit.
+	MaxErrors int
+}
+
+// CopyResult is the result of CopyIn.
+type CopyResult struct {
+	// Rows is the number of inserted or merged rows.
+	Rows int64
+	// Errors are the rows which couldn't be copied, numbered from 0
+	// in the order of the RowSource.
+	Errors []RejectedRow
+}
+
+const (
+	copyInitialBatch = 128
+	copyMaxBatch     = 8192
+)
+
+// CopyIn copies the rows of src into the columns of the table, with array
+// DML: the rows are batched into nullable slices ([]Int64, []Float64,
+// []String, []Time, []Bool and []Raw) per column, which type is chosen by
+// the first non-nil value of the column in the batch; a column mixing
+// integers and floats is bound as []Float64.
+//
+// The rows which fail (conversion, constraint violation ...) don't stop the
+// copy, but are returned in CopyResult.Errors.
+//
+// The copy is not committed: use a Tx, or the auto-commit of the session.
+func (ses *Ses) CopyIn(table string, columns []string, src RowSource, opts CopyOptions) (res CopyResult, err error) {
+	// the errors of the batches, and of the rows read meanwhile, are mixed
+	defer func() { sort.Stable(rejectedRows(res.Errors)) }()
+	if err := ses.checkClosed(); err != nil {
+		return res, errE(err)
+	}
+	if len(columns) == 0 {
+		return res, errNew("no columns to copy")
+	}
+	stmt, err := ses.Prep(copySQL(table, columns, opts.Keys))
+	if err != nil {
+		return res, err
+	}
+	defer stmt.Close()
+	stmt.Lock()
+	stmt.batchErrors = true
+	stmt.Unlock()
+
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = copyMaxBatch
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = copyInitialBatch
+	}
+	if batchSize > opts.MaxBatchSize {
+		batchSize = opts.MaxBatchSize
+	}
+
+	rows := make([][]interface{}, 0, batchSize)
+	rowNums := make([]int64, 0, batchSize)
+	var rowNum int64
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		start := time.Now()
+		n, err := stmt.copyBatch(len(columns), rows, rowNums, &res)
+		res.Rows += n
+		rows, rowNums = rows[:0], rowNums[:0]
+		if err != nil || opts.BatchSize > 0 {
+			return err
+		}
+		switch dur := time.Since(start); {
+		case dur < 100*time.Millisecond && 2*batchSize <= opts.MaxBatchSize:
+			batchSize *= 2
+		case dur > time.Second && batchSize > 1:
+			batchSize /= 2
+		}
+		return nil
+	}
+	for src.Next() {
+		if opts.MaxErrors > 0 && len(res.Errors) >= opts.MaxErrors {
+			return res, errF("too many errors (%d)", len(res.Errors))
+		}
+		values, err := src.Values()
+		switch {
+		case err != nil:
+			res.Errors = append(res.Errors, RejectedRow{Row: rowNum, Err: err})
+		case len(values) != len(columns):
+			res.Errors = append(res.Errors, RejectedRow{Row: rowNum,
+				Err: errF("got %d values, wanted %d", len(values), len(columns))})
+		default:
+			rows = append(rows, values)
+			rowNums = append(rowNums, rowNum)
+		}
+		rowNum++
+		if len(rows) >= batchSize {
+			if err = flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err = src.Err(); err != nil {
+		return res, err
+	}
+	return res, flush()
+}
+
+// copySQL returns the INSERT, or with keys, the MERGE statement of CopyIn.
+func copySQL(table string, columns, keys []string) string {
+	var buf bytes.Buffer
+	if len(keys) == 0 {
+		fmt.Fprintf(&buf, "INSERT INTO %s (%s) VALUES (", table, strings.Join(columns, ", "))
+		for i := range columns {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, ":%d", i+1)
+		}
+		buf.WriteString(")")
+		return buf.String()
+	}
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[copyColName(k)] = true
+	}
+	fmt.Fprintf(&buf, "MERGE INTO %s T USING (SELECT ", table)
+	for i, col := range columns {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, ":%d %s", i+1, col)
+	}
+	buf.WriteString(" FROM DUAL) S ON (")
+	for i, k := range keys {
+		if i != 0 {
+			buf.WriteString(" AND ")
+		}
+		fmt.Fprintf(&buf, "T.%s = S.%s", k, k)
+	}
+	buf.WriteString(")")
+	var n int
+	for _, col := range columns {
+		if isKey[copyColName(col)] {
+			continue
+		}
+		if n == 0 {
+			buf.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		} else {
+			buf.WriteString(", ")
+		}
+		n++
+		fmt.Fprintf(&buf, "T.%s = S.%s", col, col)
+	}
+	fmt.Fprintf(&buf, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (S.%s)",
+		strings.Join(columns, ", "), strings.Join(columns, ", S."))
+	return buf.String()
+}
+
+// copyColName returns the name of the column as stored in the dictionary:
+// the quoted name without the quotes, the unquoted one in upper case.
+func copyColName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+		return name[1 : len(name)-1]
+	}
+	return strings.ToUpper(name)
+}
+
+// copyBatch binds the rows as one slice per column, and executes stmt.
+// The rows which can't be bound, or fail, are appended to res.Errors.
+func (stmt *Stmt) copyBatch(ncols int, rows [][]interface{}, rowNums []int64, res *CopyResult) (int64, error) {
+	cols := make([]copyColumn, ncols)
+	for j := range cols {
+		for _, row := range rows {
+			switch k := copyKindOf(row[j]); {
+			case cols[j].kind == copyNull:
+				cols[j].kind = k
+			case cols[j].kind == copyInt && k == copyFloat:
+				cols[j].kind = copyFloat
+			}
+		}
+		cols[j].init(len(rows))
+	}
+	good := rowNums[:0:0]
+Rows:
+	for i, row := range rows {
+		for j := range cols {
+			if err := cols[j].check(row[j]); err != nil {
+				res.Errors = append(res.Errors, RejectedRow{Row: rowNums[i], Err: errF("column %d: %v", j+1, err)})
+				continue Rows
+			}
+		}
+		for j := range cols {
+			cols[j].append(row[j])
+		}
+		good = append(good, rowNums[i])
+	}
+	if len(good) == 0 {
+		return 0, nil
+	}
+	params := make([]interface{}, ncols)
+	for j := range cols {
+		params[j] = cols[j].slice()
+	}
+	n, err := stmt.Exe(params...)
+	if err != nil {
+		return 0, err
+	}
+	stmt.RLock()
+	for _, re := range stmt.rowErrors {
+		if re.Row >= 0 && int(re.Row) < len(good) {
+			re.Row = good[re.Row]
+		}
+		res.Errors = append(res.Errors, re)
+	}
+	stmt.RUnlock()
+	return int64(n), nil
+}
+
+// rejectedRows sorts the RejectedRows by Row.
+type rejectedRows []RejectedRow
+
+func (rr rejectedRows) Len() int           { return len(rr) }
+func (rr rejectedRows) Less(i, j int) bool { return rr[i].Row < rr[j].Row }
+func (rr rejectedRows) Swap(i, j int)      { rr[i], rr[j] = rr[j], rr[i] }
+
+type copyKind uint8
+
+const (
+	copyNull copyKind = iota
+	copyInt
+	copyFloat
+	copyString
+	copyTime
+	copyBool
+	copyRaw
+	copyUnsupported
+)
+
+// copyColumn collects the values of a column of a batch into a nullable slice.
+type copyColumn struct {
+	kind    copyKind
+	ints    []Int64
+	floats  []Float64
+	strings []String
+	times   []Time
+	bools   []Bool
+	raws    []Raw
+}
+
+func (c *copyColumn) init(n int) {
+	switch c.kind {
+	case copyInt:
+		c.ints = make([]Int64, 0, n)
+	case copyFloat:
+		c.floats = make([]Float64, 0, n)
+	case copyTime:
+		c.times = make([]Time, 0, n)
+	case copyBool:
+		c.bools = make([]Bool, 0, n)
+	case copyRaw:
+		c.raws = make([]Raw, 0, n)
+	default:
+		c.strings = make([]String, 0, n)
+	}
+}
+
+func (c *copyColumn) slice() interface{} {
+	switch c.kind {
+	case copyInt:
+		return c.ints
+	case copyFloat:
+		return c.floats
+	case copyTime:
+		return c.times
+	case copyBool:
+		return c.bools
+	case copyRaw:
+		return c.raws
+	default:
+		return c.strings
+	}
+}
+
+// check returns an error if v can't be appended to the column.
+func (c *copyColumn) check(v interface{}) error {
+	v = copyValue(v)
+	k := copyKindOf(v)
+	if k == copyUnsupported {
+		return errF("unsupported type %T", v)
+	}
+	if k == copyNull || k == c.kind || c.kind == copyFloat && k == copyInt {
+		return nil
+	}
+	return errF("%T does not match the type of the column in the batch", v)
+}
+
+// append appends v, which passed check.
+func (c *copyColumn) append(v interface{}) {
+	v = copyValue(v)
+	null := v == nil
+	rv := reflect.ValueOf(v)
+	switch c.kind {
+	case copyInt:
+		var x int64
+		if !null {
+			x = copyInt64(rv)
+		}
+		c.ints = append(c.ints, Int64{IsNull: null, Value: x})
+	case copyFloat:
+		var x float64
+		if !null {
+			if copyKindOf(v) == copyInt {
+				x = float64(copyInt64(rv))
+			} else {
+				x = rv.Float()
+			}
+		}
+		c.floats = append(c.floats, Float64{IsNull: null, Value: x})
+	case copyTime:
+		var x time.Time
+		if !null {
+			x = v.(time.Time)
+		}
+		c.times = append(c.times, Time{IsNull: null, Value: x})
+	case copyBool:
+		c.bools = append(c.bools, Bool{IsNull: null, Value: !null && rv.Bool()})
+	case copyRaw:
+		var x []byte
+		if !null {
+			x = v.([]byte)
+		}
+		c.raws = append(c.raws, Raw{IsNull: null || x == nil, Value: x})
+	default:
+		var x string
+		if !null {
+			x = rv.String()
+		}
+		c.strings = append(c.strings, String{IsNull: null, Value: x})
+	}
+}
+
+func copyInt64(rv reflect.Value) int64 {
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return math.MaxInt64
+		}
+		return int64(u)
+	}
+	return rv.Int()
+}
+
+// copyValue dereferences pointers and unwraps the nullable types: returns
+// nil for NULL.
+func copyValue(v interface{}) interface{} {
+	for v != nil {
+		switch x := v.(type) {
+		case Date:
+			if x.IsNull() {
+				return nil
+			}
+			return x.Get()
+		case date.Date:
+			if x.IsNull() {
+				return nil
+			}
+			return x.Get()
+		case time.Time, []byte:
+			return v
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Ptr:
+			if rv.IsNil() {
+				return nil
+			}
+			v = rv.Elem().Interface()
+		case reflect.Struct:
+			isNull, value := rv.FieldByName("IsNull"), rv.FieldByName("Value")
+			if isNull.Kind() != reflect.Bool || !value.IsValid() {
+				return v
+			}
+			if isNull.Bool() {
+				return nil
+			}
+			v = value.Interface()
+		default:
+			return v
+		}
+	}
+	return nil
+}
+
+// copyKindOf returns the kind of the column for v.
+func copyKindOf(v interface{}) copyKind {
+	v = copyValue(v)
+	switch v.(type) {
+	case nil:
+		return copyNull
+	case time.Time:
+		return copyTime
+	case []byte:
+		return copyRaw
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return copyInt
+	case reflect.Float32, reflect.Float64:
+		return copyFloat
+	case reflect.String:
+		return copyString
+	case reflect.Bool:
+		return copyBool
+	}
+	return copyUnsupported
+}
+
+// hasRowErrors returns whether the last execution had row errors.
+func (stmt *Stmt) hasRowErrors() bool {
+	stmt.RLock()
+	defer stmt.RUnlock()
+	return len(stmt.rowErrors) != 0
+}
+
+// getRowErrors collects the row errors of the array DML executed with
+// OCI_BATCH_ERRORS into stmt.rowErrors.
+func (stmt *Stmt) getRowErrors() error {
+	stmt.Lock()
+	stmt.rowErrors = stmt.rowErrors[:0]
+	stmt.Unlock()
+	p, err := stmt.attr(4, C.OCI_ATTR_NUM_DML_ERRORS)
+	if err != nil {
+		return err
+	}
+	n := int(*((*C.ub4)(p)))
+	C.free(p)
+	if n == 0 {
+		return nil
+	}
+	env := stmt.Env()
+	h, err := env.allocOciHandle(C.OCI_HTYPE_ERROR)
+	if err != nil {
+		return err
+	}
+	rowErrhp := (*C.OCIError)(h)
+	defer func() { env.freeOciHandle(unsafe.Pointer(rowErrhp), C.OCI_HTYPE_ERROR) }()
+	rowErrs := make([]RejectedRow, 0, n)
+	var buf [1024]C.char
+	for i := 0; i < n; i++ {
+		var rowOff C.ub4
+		var code C.sb4
+		env.RLock()
+		r := C.rowError(env.ocierr, &rowErrhp, C.ub4(i), &rowOff, &code, &buf[0], C.ub4(len(buf)))
+		env.RUnlock()
+		if r != C.OCI_SUCCESS {
+			return env.ociError()
+		}
+		msg := strings.TrimRight(C.GoString(&buf[0]), "\n")
+		rowErrs = append(rowErrs, RejectedRow{Row: int64(rowOff), Err: &ORAError{
+			code:    int(code),
+			message: msg,
+			records: []ErrorRecord{{Code: int(code), Message: msg}},
+		}})
+	}
+	stmt.Lock()
+	stmt.rowErrors = rowErrs
+	stmt.Unlock()
+	return nil
+}
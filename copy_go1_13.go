@@ -0,0 +1,21 @@
+// +build go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"database/sql"
+)
+
+// CopyInConn copies the rows of src into the table with the session of the
+// database/sql connection, as Ses.CopyIn.
+func CopyInConn(conn *sql.Conn, table string, columns []string, src RowSource, opts CopyOptions) (res CopyResult, err error) {
+	err = ConnSes(conn, func(ses *Ses) error {
+		res, err = ses.CopyIn(table, columns, src, opts)
+		return err
+	})
+	return res, err
+}
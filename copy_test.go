@@ -0,0 +1,34 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import "testing"
+
+// TestCopySQL tests that the keys match the columns, quoted or not.
+func TestCopySQL(t *testing.T) {
+	for i, tc := range []struct {
+		columns, keys []string
+		want          string
+	}{
+		{columns: []string{"id", "name"},
+			want: "INSERT INTO T (id, name) VALUES (:1, :2)"},
+		{columns: []string{"id", "name"}, keys: []string{"ID"},
+			want: "MERGE INTO T T USING (SELECT :1 id, :2 name FROM DUAL) S ON (T.ID = S.ID)" +
+				" WHEN MATCHED THEN UPDATE SET T.name = S.name" +
+				" WHEN NOT MATCHED THEN INSERT (id, name) VALUES (S.id, S.name)"},
+		{columns: []string{`"ID"`, `"NAME"`}, keys: []string{"id"},
+			want: `MERGE INTO T T USING (SELECT :1 "ID", :2 "NAME" FROM DUAL) S ON (T.id = S.id)` +
+				` WHEN MATCHED THEN UPDATE SET T."NAME" = S."NAME"` +
+				` WHEN NOT MATCHED THEN INSERT ("ID", "NAME") VALUES (S."ID", S."NAME")`},
+		{columns: []string{`"id"`, "name"}, keys: []string{"id"},
+			want: `MERGE INTO T T USING (SELECT :1 "id", :2 name FROM DUAL) S ON (T.id = S.id)` +
+				` WHEN MATCHED THEN UPDATE SET T."id" = S."id", T.name = S.name` +
+				` WHEN NOT MATCHED THEN INSERT ("id", name) VALUES (S."id", S.name)`},
+	} {
+		if got := copySQL("T", tc.columns, tc.keys); got != tc.want {
+			t.Errorf("%d. got\n%s\nwanted\n%s", i, got, tc.want)
+		}
+	}
+}
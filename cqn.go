@@ -0,0 +1,464 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <oci.h>
+
+extern ub4 goSubscrCallback(void *ctx, OCISubscription *subscrhp, void *payload, ub4 payl, void *descriptor, ub4 mode);
+
+static sword setSubscrCallback(OCISubscription *subscrhp, OCIError *errhp, uintptr_t id) {
+	sword r = OCIAttrSet(subscrhp, OCI_HTYPE_SUBSCRIPTION, (void *)goSubscrCallback, 0, OCI_ATTR_SUBSCR_CALLBACK, errhp);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	return OCIAttrSet(subscrhp, OCI_HTYPE_SUBSCRIPTION, (void *)id, 0, OCI_ATTR_SUBSCR_CTX, errhp);
+}
+
+static sword setSubscrReg(OCIStmt *stmthp, OCISubscription *subscrhp, OCIError *errhp) {
+	return OCIAttrSet(stmthp, OCI_HTYPE_STMT, subscrhp, 0, OCI_ATTR_CHNF_REGHANDLE, errhp);
+}
+
+// collElem returns the i-th descriptor of the collection of change descriptors.
+static sword collElem(OCIEnv *envhp, OCIError *errhp, OCIColl *coll, sb4 i, void **desc) {
+	boolean exists;
+	void *elem, *ind;
+	sword r = OCICollGetElem(envhp, errhp, coll, i, &exists, &elem, &ind);
+	*desc = NULL;
+	if (r == OCI_SUCCESS && exists) {
+		*desc = *(void **)elem;
+	}
+	return r;
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// EventType is the type of a change notification Event.
+type EventType uint32
+
+// Event types.
+const (
+	EventNone     = EventType(C.OCI_EVENT_NONE)
+	EventStartup  = EventType(C.OCI_EVENT_STARTUP)
+	EventShutdown = EventType(C.OCI_EVENT_SHUTDOWN)
+	EventDereg    = EventType(C.OCI_EVENT_DEREG)
+	EventObject   = EventType(C.OCI_EVENT_OBJCHANGE)
+	EventQuery    = EventType(C.OCI_EVENT_QUERYCHANGE)
+)
+
+// Operation is the bit set of the operations of a change.
+type Operation uint32
+
+// Operations.
+const (
+	// OpAllRows means that all the rows of the table changed,
+	// or the ROWIDs are not available.
+	OpAllRows = Operation(C.OCI_OPCODE_ALLROWS)
+	OpInsert  = Operation(C.OCI_OPCODE_INSERT)
+	OpUpdate  = Operation(C.OCI_OPCODE_UPDATE)
+	OpDelete  = Operation(C.OCI_OPCODE_DELETE)
+	OpAlter   = Operation(C.OCI_OPCODE_ALTER)
+	OpDrop    = Operation(C.OCI_OPCODE_DROP)
+	OpAll     = Operation(C.OCI_OPCODE_ALLOPS)
+)
+
+// Event is a change notification.
+type Event struct {
+	Type EventType
+	// DB is the name of the database.
+	DB string
+	// Tables are the changed tables, for EventObject.
+	Tables []TableChange
+	// Queries are the queries with changed result, for EventQuery.
+	Queries []QueryChange
+}
+
+// TableChange is the change of a table.
+type TableChange struct {
+	// Name is the name of the table as SCHEMA.TABLE.
+	Name      string
+	Operation Operation
+	// Rows are the changed rows, if SubscribeOptions.Rowids is set,
+	// and Operation does not have OpAllRows.
+	Rows []RowChange
+}
+
+// RowChange is the change of a row.
+type RowChange struct {
+	Operation Operation
+	Rowid     string
+}
+
+// QueryChange is the change of the result of a registered query.
+type QueryChange struct {
+	// ID is the query ID returned by Subscription.Add.
+	ID        uint64
+	Operation Operation
+	Tables    []TableChange
+}
+
+// SubscribeOptions are the options of Ses.Subscribe.
+type SubscribeOptions struct {
+	// Query makes the subscription query-level (Continuous Query Notification):
+	// notify only when the result of a registered query changes;
+	// otherwise, notify on any change of the tables of the queries.
+	Query bool
+	// Rowids requests the ROWIDs of the changed rows.
+	Rowids bool
+	// Operations filters the notifications by operation; 0 means all.
+	Operations Operation
+	// Timeout is the time after which the server removes the subscription;
+	// 0 means never.
+	Timeout time.Duration
+	// Reliable makes the notifications persistent, to survive instance failures.
+	Reliable bool
+	// Port is the client port the server connects to for the notifications;
+	// 0 lets the client choose.
+	Port int
+	// ChanSize is the buffer size of the Events channel; the default is 16.
+	ChanSize int
+}
+
+// Subscription is a database change notification subscription.
+//
+// The events are delivered on the channel returned by Events,
+// which is closed by Close. The channel must be drained, as a full
+// channel blocks the notification thread of OCI.
+type Subscription struct {
+	mu        sync.RWMutex // held for writing while the handles are freed
+	id        uint64
+	ses       *Ses
+	env       *Env
+	ocisubscr *C.OCISubscription
+	ocierr    *C.OCIError // used by the notification thread
+	events    chan Event
+	done      chan struct{}
+	closed    int32
+}
+
+var (
+	subscrs  registry
+	subscrID uint64
+)
+
+// Subscribe registers a database change notification subscription
+// with the queries, and returns it.
+//
+// The environment must have been opened with DrvCfg.Events set,
+// and the user needs the CHANGE NOTIFICATION privilege.
+func (ses *Ses) Subscribe(opts SubscribeOptions, queries ...string) (*Subscription, error) {
+	if err := ses.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	env := ses.Env()
+	env.RLock()
+	events := env.events
+	env.RUnlock()
+	if !events {
+		return nil, errNew("Subscribe needs an Env opened with DrvCfg.Events set")
+	}
+	if opts.ChanSize <= 0 {
+		opts.ChanSize = 16
+	}
+	sub := &Subscription{
+		id:     atomic.AddUint64(&subscrID, 1),
+		ses:    ses,
+		env:    env,
+		events: make(chan Event, opts.ChanSize),
+		done:   make(chan struct{}),
+	}
+	if err := sub.register(opts); err != nil {
+		sub.free()
+		return nil, err
+	}
+	ses.openSubscrs.add(sub)
+	for _, qry := range queries {
+		if _, err := sub.Add(qry); err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+// register allocates and registers the subscription handle.
+func (sub *Subscription) register(opts SubscribeOptions) error {
+	env := sub.env
+	h, err := env.allocOciHandle(C.OCI_HTYPE_SUBSCRIPTION)
+	if err != nil {
+		return errE(err)
+	}
+	sub.ocisubscr = (*C.OCISubscription)(h)
+	if h, err = env.allocOciHandle(C.OCI_HTYPE_ERROR); err != nil {
+		return errE(err)
+	}
+	sub.ocierr = (*C.OCIError)(h)
+
+	setUb4 := func(value C.ub4, attr C.ub4) error {
+		return env.setAttr(unsafe.Pointer(sub.ocisubscr), C.OCI_HTYPE_SUBSCRIPTION,
+			unsafe.Pointer(&value), 4, attr)
+	}
+	if err = setUb4(C.OCI_SUBSCR_NAMESPACE_DBCHANGE, C.OCI_ATTR_SUBSCR_NAMESPACE); err != nil {
+		return err
+	}
+	env.RLock()
+	r := C.setSubscrCallback(sub.ocisubscr, env.ocierr, C.uintptr_t(sub.id))
+	env.RUnlock()
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	if opts.Rowids {
+		rowids := C.boolean(1)
+		if err = env.setAttr(unsafe.Pointer(sub.ocisubscr), C.OCI_HTYPE_SUBSCRIPTION,
+			unsafe.Pointer(&rowids), C.ub4(unsafe.Sizeof(rowids)), C.OCI_ATTR_CHNF_ROWIDS); err != nil {
+			return err
+		}
+	}
+	if opts.Query {
+		if err = setUb4(C.OCI_SUBSCR_CQ_QOS_QUERY, C.OCI_ATTR_SUBSCR_CQ_QOSFLAGS); err != nil {
+			return err
+		}
+	}
+	if opts.Operations != 0 {
+		if err = setUb4(C.ub4(opts.Operations), C.OCI_ATTR_CHNF_OPERATIONS); err != nil {
+			return err
+		}
+	}
+	if opts.Timeout > 0 {
+		if err = setUb4(C.ub4(roundSeconds(opts.Timeout)), C.OCI_ATTR_SUBSCR_TIMEOUT); err != nil {
+			return err
+		}
+	}
+	if opts.Reliable {
+		if err = setUb4(C.OCI_SUBSCR_QOS_RELIABLE, C.OCI_ATTR_SUBSCR_QOSFLAGS); err != nil {
+			return err
+		}
+	}
+	if opts.Port > 0 {
+		if err = setUb4(C.ub4(opts.Port), C.OCI_ATTR_SUBSCR_PORTNO); err != nil {
+			return err
+		}
+	}
+
+	subscrs.add(sub.id, sub)
+	sub.ses.RLock()
+	env.RLock()
+	r = C.OCISubscriptionRegister(sub.ses.ocisvcctx, &sub.ocisubscr, 1, env.ocierr, C.OCI_DEFAULT)
+	env.RUnlock()
+	sub.ses.RUnlock()
+	if r == C.OCI_ERROR {
+		err = env.ociError()
+		subscrs.remove(sub.id)
+		return err
+	}
+	return nil
+}
+
+// Add registers the query with the subscription, and returns its query ID
+// (only for query-level subscriptions, otherwise 0).
+//
+// The query is executed, with the params, but its rows are not fetched.
+func (sub *Subscription) Add(query string, params ...interface{}) (queryID uint64, err error) {
+	sub.mu.RLock() // keeps close from freeing the handle meanwhile
+	defer sub.mu.RUnlock()
+	if atomic.LoadInt32(&sub.closed) != 0 {
+		return 0, errNew("Subscription is closed")
+	}
+	stmt, err := sub.ses.Prep(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	env := sub.env
+	stmt.RLock()
+	env.RLock()
+	r := C.setSubscrReg(stmt.ocistmt, sub.ocisubscr, env.ocierr)
+	env.RUnlock()
+	stmt.RUnlock()
+	if r == C.OCI_ERROR {
+		return 0, env.ociError()
+	}
+	rset, err := stmt.Qry(params...)
+	if err != nil {
+		return 0, err
+	}
+	if err = rset.cancel(); err != nil {
+		return 0, err
+	}
+	if p, attrErr := stmt.attr(8, C.OCI_ATTR_CQ_QUERYID); attrErr == nil {
+		queryID = uint64(*((*C.ub8)(p)))
+		C.free(p)
+	}
+	return queryID, nil
+}
+
+// Events returns the channel of the notifications.
+func (sub *Subscription) Events() <-chan Event { return sub.events }
+
+// Close unregisters the subscription, and closes the Events channel.
+func (sub *Subscription) Close() error {
+	if sub.ses != nil {
+		sub.ses.openSubscrs.remove(sub)
+	}
+	return sub.close()
+}
+
+// close unregisters the subscription, and closes the Events channel.
+// close does not remove the Subscription from Ses.openSubscrs.
+func (sub *Subscription) close() error {
+	if !atomic.CompareAndSwapInt32(&sub.closed, 0, 1) {
+		return nil
+	}
+	close(sub.done) // unblocks the callback
+	var err error
+	sub.ses.RLock()
+	ocisvcctx := sub.ses.ocisvcctx
+	sub.ses.RUnlock()
+	if ocisvcctx != nil {
+		sub.env.RLock()
+		r := C.OCISubscriptionUnRegister(ocisvcctx, sub.ocisubscr, sub.env.ocierr, C.OCI_DEFAULT)
+		sub.env.RUnlock()
+		if r == C.OCI_ERROR {
+			err = sub.env.ociError()
+		}
+	}
+	subscrs.remove(sub.id)
+	sub.mu.Lock() // waits for the running callback
+	sub.free()
+	close(sub.events)
+	sub.mu.Unlock()
+	return err
+}
+
+// free frees the OCI handles of the subscription.
+func (sub *Subscription) free() {
+	if sub.ocisubscr != nil {
+		sub.env.freeOciHandle(unsafe.Pointer(sub.ocisubscr), C.OCI_HTYPE_SUBSCRIPTION)
+		sub.ocisubscr = nil
+	}
+	if sub.ocierr != nil {
+		sub.env.freeOciHandle(unsafe.Pointer(sub.ocierr), C.OCI_HTYPE_ERROR)
+		sub.ocierr = nil
+	}
+}
+
+// notify parses the change descriptor, and delivers the event,
+// unless the subscription is closed.
+//
+// The read lock keeps close from freeing the handles meanwhile.
+func (sub *Subscription) notify(desc unsafe.Pointer) {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+	if atomic.LoadInt32(&sub.closed) != 0 {
+		return
+	}
+	sub.deliver(sub.parseEvent(desc))
+}
+
+// deliver sends the event on the Events channel, or drops it when
+// the subscription gets closed. No locking occurs.
+func (sub *Subscription) deliver(event Event) {
+	select {
+	case sub.events <- event:
+	case <-sub.done:
+	}
+}
+
+// parseEvent parses the change descriptor.
+// It runs on the notification thread, using the own error handle of the subscription.
+func (sub *Subscription) parseEvent(desc unsafe.Pointer) Event {
+	var event Event
+	event.Type = EventType(sub.ub4Attr(desc, C.OCI_DTYPE_CHDES, C.OCI_ATTR_CHDES_NFYTYPE))
+	event.DB = sub.textAttr(desc, C.OCI_DTYPE_CHDES, C.OCI_ATTR_CHDES_DBNAME)
+	switch event.Type {
+	case EventObject:
+		event.Tables = sub.tableChanges(sub.collAttr(desc, C.OCI_DTYPE_CHDES, C.OCI_ATTR_CHDES_TABLE_CHANGES))
+	case EventQuery:
+		coll := sub.collAttr(desc, C.OCI_DTYPE_CHDES, C.OCI_ATTR_CHDES_QUERIES)
+		sub.eachElem(coll, func(q unsafe.Pointer) {
+			var id C.ub8
+			C.OCIAttrGet(q, C.OCI_DTYPE_CQDES, unsafe.Pointer(&id), nil, C.OCI_ATTR_CQDES_QUERYID, sub.ocierr)
+			event.Queries = append(event.Queries, QueryChange{
+				ID:        uint64(id),
+				Operation: Operation(sub.ub4Attr(q, C.OCI_DTYPE_CQDES, C.OCI_ATTR_CQDES_OPERATION)),
+				Tables:    sub.tableChanges(sub.collAttr(q, C.OCI_DTYPE_CQDES, C.OCI_ATTR_CQDES_TABLE_CHANGES)),
+			})
+		})
+	}
+	return event
+}
+
+func (sub *Subscription) tableChanges(coll *C.OCIColl) []TableChange {
+	var tables []TableChange
+	sub.eachElem(coll, func(t unsafe.Pointer) {
+		tc := TableChange{
+			Name:      sub.textAttr(t, C.OCI_DTYPE_TABLE_CHDES, C.OCI_ATTR_CHDES_TABLE_NAME),
+			Operation: Operation(sub.ub4Attr(t, C.OCI_DTYPE_TABLE_CHDES, C.OCI_ATTR_CHDES_TABLE_OPFLAGS)),
+		}
+		if tc.Operation&OpAllRows == 0 {
+			rows := sub.collAttr(t, C.OCI_DTYPE_TABLE_CHDES, C.OCI_ATTR_CHDES_TABLE_ROW_CHANGES)
+			sub.eachElem(rows, func(r unsafe.Pointer) {
+				tc.Rows = append(tc.Rows, RowChange{
+					Operation: Operation(sub.ub4Attr(r, C.OCI_DTYPE_ROW_CHDES, C.OCI_ATTR_CHDES_ROW_OPFLAGS)),
+					Rowid:     sub.textAttr(r, C.OCI_DTYPE_ROW_CHDES, C.OCI_ATTR_CHDES_ROW_ROWID),
+				})
+			})
+		}
+		tables = append(tables, tc)
+	})
+	return tables
+}
+
+func (sub *Subscription) ub4Attr(desc unsafe.Pointer, typ, attr C.ub4) C.ub4 {
+	var v C.ub4
+	C.OCIAttrGet(desc, typ, unsafe.Pointer(&v), nil, attr, sub.ocierr)
+	return v
+}
+
+func (sub *Subscription) textAttr(desc unsafe.Pointer, typ, attr C.ub4) string {
+	var p *C.char
+	var n C.ub4
+	if C.OCIAttrGet(desc, typ, unsafe.Pointer(&p), &n, attr, sub.ocierr) != C.OCI_SUCCESS || p == nil {
+		return ""
+	}
+	return C.GoStringN(p, C.int(n))
+}
+
+func (sub *Subscription) collAttr(desc unsafe.Pointer, typ, attr C.ub4) *C.OCIColl {
+	var coll *C.OCIColl
+	if C.OCIAttrGet(desc, typ, unsafe.Pointer(&coll), nil, attr, sub.ocierr) != C.OCI_SUCCESS {
+		return nil
+	}
+	return coll
+}
+
+// eachElem calls f with each descriptor of the collection.
+func (sub *Subscription) eachElem(coll *C.OCIColl, f func(unsafe.Pointer)) {
+	if coll == nil {
+		return
+	}
+	sub.env.RLock()
+	ocienv := sub.env.ocienv
+	sub.env.RUnlock()
+	var n C.sb4
+	if C.OCICollSize(ocienv, sub.ocierr, coll, &n) != C.OCI_SUCCESS {
+		return
+	}
+	for i := C.sb4(0); i < n; i++ {
+		var desc unsafe.Pointer
+		if C.collElem(ocienv, sub.ocierr, coll, i, &desc) == C.OCI_SUCCESS && desc != nil {
+			f(desc)
+		}
+	}
+}
@@ -25,3 +25,24 @@ func ctxStmtCfg(ctx context.Context) (StmtCfg, bool) {
 func WithStmtCfg(ctx context.Context, cfg StmtCfg) context.Context {
 	return context.WithValue(ctx, stmtCfgKey, cfg)
 }
+
+// runCtx runs f, and Breaks the session if ctx is done before f returns.
+// In that case, it waits for f to return, and returns ctx.Err().
+func runCtx(ctx context.Context, ses *Ses, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil || ses == nil {
+		return f()
+	}
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		ses.Break()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -312,6 +312,7 @@ func (lr *lobReader) Read(p []byte) (n int, err error) {
 		csfrm,                 //lr.charsetForm,                          //ub1                csfrm );
 	)
 	//Log.Infof("LobRead2 returned %d amt=%d", r, byteAmt)
+	_stats.lobRW(uint64(byteAmt), 0)
 	err = nil
 	switch r {
 	case C.OCI_ERROR:
@@ -429,6 +430,10 @@ func (lrw *lobReadWriter) ReadAt(p []byte, off int64) (n int, err error) {
 		lrw.csfrm,              //ub1                csfrm );
 	)
 	//Log.Infof("LobRead2 returned %d amt=%d", r, byteAmt)
+	if r == C.OCI_ERROR {
+		byteAmt = 0
+	}
+	_stats.lobRW(uint64(byteAmt), 0)
 	switch r {
 	case C.OCI_ERROR:
 		return 0, lrw.ses.srv.env.ociError()
@@ -462,8 +467,10 @@ func (lrw *lobReadWriter) WriteAt(p []byte, off int64) (n int, err error) {
 		C.SQLCS_IMPLICIT,                        //ub1             csfrm );
 	//fmt.Printf("C.OCI_NEED_DATA %v, C.OCI_SUCCESS %v\n", C.OCI_NEED_DATA, C.OCI_SUCCESS)
 	) == C.OCI_ERROR {
+		_stats.lobRW(0, 0)
 		return 0, lrw.ses.srv.env.ociError()
 	}
+	_stats.lobRW(0, uint64(byteAmt))
 	//fmt.Printf("r %v, current %v, buffer %v\n", r, current, buffer)
 	if C.oraub8(off)+byteAmt > lrw.size {
 		lrw.size = C.oraub8(off) + byteAmt
@@ -0,0 +1,438 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <stdlib.h>
+#include <oci.h>
+
+// descParam returns the top-level parameter of the describe handle.
+static sword descParam(OCIDescribe *dschp, OCIError *errhp, OCIParam **parmh) {
+	return OCIAttrGet(dschp, OCI_HTYPE_DESCRIBE, parmh, NULL, OCI_ATTR_PARAM, errhp);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// DescribedColumn type for describing a column (see DescribeQuery).
+type DescribedColumn struct {
+	Column
+}
+
+// ObjectType is the type of an object described by Ses.Describe.
+type ObjectType uint8
+
+// Object types.
+const (
+	ObjUnknown ObjectType = iota
+	ObjTable
+	ObjView
+	ObjSynonym
+	ObjProcedure
+	ObjFunction
+	ObjPackage
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjTable:
+		return "TABLE"
+	case ObjView:
+		return "VIEW"
+	case ObjSynonym:
+		return "SYNONYM"
+	case ObjProcedure:
+		return "PROCEDURE"
+	case ObjFunction:
+		return "FUNCTION"
+	case ObjPackage:
+		return "PACKAGE"
+	}
+	return "UNKNOWN"
+}
+
+// ArgDirection is the direction of a procedure argument.
+type ArgDirection uint8
+
+// Argument directions.
+const (
+	ArgIn ArgDirection = iota
+	ArgOut
+	ArgInOut
+)
+
+func (d ArgDirection) String() string {
+	switch d {
+	case ArgOut:
+		return "OUT"
+	case ArgInOut:
+		return "IN OUT"
+	}
+	return "IN"
+}
+
+// Argument is an argument of a described procedure or function.
+type Argument struct {
+	DescribedColumn
+
+	// Position is the position of the argument, 0 for the return value
+	// of functions.
+	Position int
+	// Level is the nesting level of the argument (0 for the top level).
+	Level      int
+	Direction  ArgDirection
+	HasDefault bool
+}
+
+// Subprogram is a procedure or function of a described package.
+type Subprogram struct {
+	Name string
+	// Overload is the overload ID of the subprogram, 0 if it is not overloaded.
+	Overload int
+	Function bool
+	// Arguments of the subprogram, starting with the return value of functions.
+	Arguments []Argument
+}
+
+// Object is the description of a schema object, returned by Ses.Describe.
+type Object struct {
+	Schema, Name string
+	Type         ObjectType
+
+	// Columns of tables and views.
+	Columns []DescribedColumn
+	// Arguments of procedures and functions,
+	// starting with the return value of functions.
+	Arguments []Argument
+	// Subprograms of packages.
+	Subprograms []Subprogram
+	// Translated object of synonyms: schema, name and database link.
+	SynonymSchema, SynonymName, SynonymLink string
+}
+
+// describer reads the attributes of describe parameters.
+type describer struct {
+	env *Env
+}
+
+func (d describer) attr(p *C.OCIParam, value unsafe.Pointer, size *C.ub4, attrType C.ub4) error {
+	if size == nil {
+		size = new(C.ub4)
+	}
+	if r := C.OCIAttrGet(unsafe.Pointer(p), C.OCI_DTYPE_PARAM, value, size, attrType, d.env.ocierr); r == C.OCI_ERROR {
+		return d.env.ociError()
+	}
+	return nil
+}
+
+func (d describer) ub1(p *C.OCIParam, attrType C.ub4) (int, error) {
+	var v C.ub1
+	err := d.attr(p, unsafe.Pointer(&v), nil, attrType)
+	return int(v), err
+}
+
+func (d describer) ub2(p *C.OCIParam, attrType C.ub4) (int, error) {
+	var v C.ub2
+	err := d.attr(p, unsafe.Pointer(&v), nil, attrType)
+	return int(v), err
+}
+
+func (d describer) text(p *C.OCIParam, attrType C.ub4) (string, error) {
+	var s *C.char
+	var n C.ub4
+	if err := d.attr(p, unsafe.Pointer(&s), &n, attrType); err != nil || s == nil {
+		return "", err
+	}
+	return C.GoStringN(s, C.int(n)), nil
+}
+
+// list returns the list parameter of p, and the number of its elements.
+func (d describer) list(p *C.OCIParam, attrType C.ub4) (*C.OCIParam, int, error) {
+	var list *C.OCIParam
+	if err := d.attr(p, unsafe.Pointer(&list), nil, attrType); err != nil {
+		return nil, 0, err
+	}
+	n, err := d.ub2(list, C.OCI_ATTR_NUM_PARAMS)
+	return list, n, err
+}
+
+// elem returns the pos-th element of the list.
+func (d describer) elem(list *C.OCIParam, pos int) (*C.OCIParam, error) {
+	var p *C.OCIParam
+	if r := C.OCIParamGet(unsafe.Pointer(list), C.OCI_DTYPE_PARAM, d.env.ocierr,
+		(*unsafe.Pointer)(unsafe.Pointer(&p)), C.ub4(pos)); r == C.OCI_ERROR {
+		return nil, d.env.ociError()
+	}
+	return p, nil
+}
+
+// column describes the column (or argument) parameter.
+// The precision is ub1 for explicit, sb2 for implicit (select-list) describes.
+func (d describer) column(p *C.OCIParam, explicit bool) (Column, error) {
+	var col Column
+	var err error
+	if col.Name, err = d.text(p, C.OCI_ATTR_NAME); err != nil {
+		return col, err
+	}
+	var typ, size C.ub2
+	if err = d.attr(p, unsafe.Pointer(&typ), nil, C.OCI_ATTR_DATA_TYPE); err != nil {
+		return col, err
+	}
+	if err = d.attr(p, unsafe.Pointer(&size), nil, C.OCI_ATTR_DATA_SIZE); err != nil {
+		return col, err
+	}
+	col.Type, col.Length = OracleType(typ), uint32(size)
+	if explicit {
+		var precision C.ub1
+		err = d.attr(p, unsafe.Pointer(&precision), nil, C.OCI_ATTR_PRECISION)
+		col.Precision = int16(precision)
+	} else {
+		var precision C.sb2
+		err = d.attr(p, unsafe.Pointer(&precision), nil, C.OCI_ATTR_PRECISION)
+		col.Precision = int16(precision)
+	}
+	if err != nil {
+		return col, err
+	}
+	var scale C.sb1
+	if err = d.attr(p, unsafe.Pointer(&scale), nil, C.OCI_ATTR_SCALE); err != nil {
+		return col, err
+	}
+	col.Scale = int8(scale)
+	// arguments don't have these
+	if isNull, err := d.ub1(p, C.OCI_ATTR_IS_NULL); err == nil {
+		col.Nullable = isNull != 0
+	}
+	col.CharsetID, _ = d.ub2(p, C.OCI_ATTR_CHARSET_ID)
+	col.CharsetForm, _ = d.ub1(p, C.OCI_ATTR_CHARSET_FORM)
+	if charLength, err := d.ub2(p, C.OCI_ATTR_CHAR_SIZE); err == nil {
+		col.CharLength = uint32(charLength)
+	}
+	charUsed, _ := d.ub1(p, C.OCI_ATTR_CHAR_USED)
+	col.CharUsed = charUsed != 0
+	if col.Type == TypeNamed || col.Type == TypeRef {
+		col.Schema, _ = d.text(p, C.OCI_ATTR_SCHEMA_NAME)
+		col.TypeName, _ = d.text(p, C.OCI_ATTR_TYPE_NAME)
+	}
+	return col, nil
+}
+
+// arguments describes the top-level arguments of the procedure or function.
+func (d describer) arguments(p *C.OCIParam, function bool) ([]Argument, error) {
+	list, n, err := d.list(p, C.OCI_ATTR_LIST_ARGUMENTS)
+	if err != nil {
+		return nil, err
+	}
+	// the return value of functions is at position 0
+	first := 1
+	if function {
+		first, n = 0, n-1
+	}
+	args := make([]Argument, 0, n+1)
+	for pos := first; pos <= n; pos++ {
+		ap, err := d.elem(list, pos)
+		if err != nil {
+			return args, err
+		}
+		var arg Argument
+		if arg.Column, err = d.column(ap, true); err != nil {
+			return args, err
+		}
+		if arg.Position, err = d.ub2(ap, C.OCI_ATTR_POSITION); err != nil {
+			return args, err
+		}
+		arg.Level, _ = d.ub2(ap, C.OCI_ATTR_LEVEL)
+		hasDefault, _ := d.ub1(ap, C.OCI_ATTR_HAS_DEFAULT)
+		arg.HasDefault = hasDefault != 0
+		var mode C.OCITypeParamMode
+		if err = d.attr(ap, unsafe.Pointer(&mode), nil, C.OCI_ATTR_IOMODE); err != nil {
+			return args, err
+		}
+		switch mode {
+		case C.OCI_TYPEPARAM_OUT:
+			arg.Direction = ArgOut
+		case C.OCI_TYPEPARAM_INOUT:
+			arg.Direction = ArgInOut
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// Describe describes the select-list of the prepared query,
+// without executing it.
+func (stmt *Stmt) Describe() ([]DescribedColumn, error) {
+	stmt.log(_drv.Cfg().Log.Stmt.Qry)
+	if err := stmt.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	stmt.RLock()
+	cols, err := stmt.describe()
+	stmt.RUnlock()
+	if err != nil {
+		// stmtErr locks stmt
+		return nil, stmt.stmtErr(err)
+	}
+	return cols, nil
+}
+
+// describe describes the select-list of the query. No locking occurs.
+func (stmt *Stmt) describe() ([]DescribedColumn, error) {
+	if stmt.stmtType != C.OCI_STMT_SELECT {
+		return nil, errF("only queries can be described, not %q", stmt.sql)
+	}
+	env := stmt.Env()
+	ses := stmt.ses
+	ses.RLock()
+	r := C.OCIStmtExecute(ses.ocisvcctx, stmt.ocistmt, env.ocierr,
+		C.ub4(1), C.ub4(0), nil, nil, C.OCI_DESCRIBE_ONLY)
+	ses.RUnlock()
+	if r == C.OCI_ERROR {
+		return nil, errE(env.ociError())
+	}
+	var paramCount C.ub4
+	if r = C.OCIAttrGet(unsafe.Pointer(stmt.ocistmt), C.OCI_HTYPE_STMT,
+		unsafe.Pointer(&paramCount), nil, C.OCI_ATTR_PARAM_COUNT, env.ocierr); r == C.OCI_ERROR {
+		return nil, errE(env.ociError())
+	}
+	d := describer{env: env}
+	cols := make([]DescribedColumn, int(paramCount))
+	for i := range cols {
+		var p *C.OCIParam
+		if r = C.OCIParamGet(unsafe.Pointer(stmt.ocistmt), C.OCI_HTYPE_STMT, env.ocierr,
+			(*unsafe.Pointer)(unsafe.Pointer(&p)), C.ub4(i+1)); r == C.OCI_ERROR {
+			return nil, errE(env.ociError())
+		}
+		var err error
+		cols[i].Column, err = d.column(p, false)
+		C.OCIDescriptorFree(unsafe.Pointer(p), C.OCI_DTYPE_PARAM)
+		if err != nil {
+			return nil, errE(err)
+		}
+	}
+	return cols, nil
+}
+
+// DescribeQuery parses the query and returns the column types,
+// without executing it.
+func (ses *Ses) DescribeQuery(qry string) ([]DescribedColumn, error) {
+	stmt, err := ses.Prep(qry)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	return stmt.Describe()
+}
+
+// Describe describes the table, view, synonym, procedure, function or
+// package (as NAME, SCHEMA.NAME or NAME@DBLINK), with OCIDescribeAny.
+//
+// Unqualified names are looked up in the current schema, then as public synonyms.
+func (ses *Ses) Describe(objectName string) (*Object, error) {
+	ses.log(_drv.Cfg().Log.Ses.Prep, objectName)
+	if err := ses.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	env := ses.Env()
+	h, err := env.allocOciHandle(C.OCI_HTYPE_DESCRIBE)
+	if err != nil {
+		return nil, errE(err)
+	}
+	dschp := (*C.OCIDescribe)(h)
+	defer env.freeOciHandle(h, C.OCI_HTYPE_DESCRIBE)
+
+	public := C.ub1(1)
+	if r := C.OCIAttrSet(h, C.OCI_HTYPE_DESCRIBE, unsafe.Pointer(&public), 0,
+		C.OCI_ATTR_DESC_PUBLIC, env.ocierr); r == C.OCI_ERROR {
+		return nil, errE(env.ociError())
+	}
+	name := C.CString(objectName)
+	defer C.free(unsafe.Pointer(name))
+	ses.RLock()
+	r := C.OCIDescribeAny(ses.ocisvcctx, env.ocierr, unsafe.Pointer(name), C.ub4(len(objectName)),
+		C.OCI_OTYPE_NAME, C.OCI_DEFAULT, C.OCI_PTYPE_UNK, dschp)
+	ses.RUnlock()
+	if r == C.OCI_ERROR {
+		return nil, errE(env.ociError())
+	}
+	var p *C.OCIParam
+	if r = C.descParam(dschp, env.ocierr, &p); r == C.OCI_ERROR {
+		return nil, errE(env.ociError())
+	}
+
+	d := describer{env: env}
+	var obj Object
+	obj.Schema, _ = d.text(p, C.OCI_ATTR_OBJ_SCHEMA)
+	obj.Name, _ = d.text(p, C.OCI_ATTR_OBJ_NAME)
+	ptype, err := d.ub1(p, C.OCI_ATTR_PTYPE)
+	if err != nil {
+		return nil, errE(err)
+	}
+	switch ptype {
+	case C.OCI_PTYPE_TABLE, C.OCI_PTYPE_VIEW:
+		if obj.Type = ObjTable; ptype == C.OCI_PTYPE_VIEW {
+			obj.Type = ObjView
+		}
+		var list *C.OCIParam
+		if err = d.attr(p, unsafe.Pointer(&list), nil, C.OCI_ATTR_LIST_COLUMNS); err != nil {
+			return nil, errE(err)
+		}
+		n, err := d.ub2(p, C.OCI_ATTR_NUM_COLS)
+		if err != nil {
+			return nil, errE(err)
+		}
+		obj.Columns = make([]DescribedColumn, n)
+		for i := range obj.Columns {
+			cp, err := d.elem(list, i+1)
+			if err != nil {
+				return nil, errE(err)
+			}
+			if obj.Columns[i].Column, err = d.column(cp, true); err != nil {
+				return nil, errE(err)
+			}
+		}
+	case C.OCI_PTYPE_SYN:
+		obj.Type = ObjSynonym
+		obj.SynonymSchema, _ = d.text(p, C.OCI_ATTR_SCHEMA_NAME)
+		obj.SynonymName, _ = d.text(p, C.OCI_ATTR_NAME)
+		obj.SynonymLink, _ = d.text(p, C.OCI_ATTR_LINK)
+	case C.OCI_PTYPE_PROC, C.OCI_PTYPE_FUNC:
+		if obj.Type = ObjProcedure; ptype == C.OCI_PTYPE_FUNC {
+			obj.Type = ObjFunction
+		}
+		if obj.Arguments, err = d.arguments(p, obj.Type == ObjFunction); err != nil {
+			return nil, errE(err)
+		}
+	case C.OCI_PTYPE_PKG:
+		obj.Type = ObjPackage
+		list, n, err := d.list(p, C.OCI_ATTR_LIST_SUBPROGRAMS)
+		if err != nil {
+			return nil, errE(err)
+		}
+		obj.Subprograms = make([]Subprogram, n)
+		for i := range obj.Subprograms {
+			sp, err := d.elem(list, i)
+			if err != nil {
+				return nil, errE(err)
+			}
+			sub := &obj.Subprograms[i]
+			if sub.Name, err = d.text(sp, C.OCI_ATTR_NAME); err != nil {
+				return nil, errE(err)
+			}
+			sub.Overload, _ = d.ub2(sp, C.OCI_ATTR_OVERLOAD_ID)
+			subType, _ := d.ub1(sp, C.OCI_ATTR_PTYPE)
+			sub.Function = subType == C.OCI_PTYPE_FUNC
+			if sub.Arguments, err = d.arguments(sp, sub.Function); err != nil {
+				return nil, errE(err)
+			}
+		}
+	default:
+		return &obj, errF("%s: unsupported object type %d", objectName, ptype)
+	}
+	return &obj, nil
+}
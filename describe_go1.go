@@ -0,0 +1,83 @@
+// +build !go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+)
+
+// DescribeQuery parses the query and returns the column types, as
+// DBMS_SQL.describe_column does.
+//
+// Since Go 1.13 it uses the native Ses.DescribeQuery.
+func DescribeQuery(db *sql.DB, qry string) ([]DescribedColumn, error) {
+	res := bytesPool.Get(32766)
+	defer bytesPool.Put(res)
+	for i := range res {
+		res[i] = 0
+	}
+	res = make([]byte, 32766)
+	if _, err := db.Exec(`DECLARE
+  c INTEGER;
+  col_cnt INTEGER;
+  rec_tab DBMS_SQL.DESC_TAB;
+  a DBMS_SQL.DESC_REC;
+  v_idx PLS_INTEGER;
+  res VARCHAR2(32767);
+BEGIN
+  c := DBMS_SQL.OPEN_CURSOR;
+  BEGIN
+    DBMS_SQL.PARSE(c, :1, DBMS_SQL.NATIVE);
+    DBMS_SQL.DESCRIBE_COLUMNS(c, col_cnt, rec_tab);
+    v_idx := rec_tab.FIRST;
+    WHILE v_idx IS NOT NULL LOOP
+      a := rec_tab(v_idx);
+      res := res||a.col_schema_name||CHR(31)||a.col_name||CHR(31)||a.col_type||' '||
+                  a.col_max_len||' '||a.col_precision||' '||a.col_scale||' '||
+                  (CASE WHEN a.col_null_ok THEN 1 ELSE 0 END)||' '||
+                  a.col_charsetid||' '||a.col_charsetform||
+                  CHR(10);
+      v_idx := rec_tab.NEXT(v_idx);
+    END LOOP;
+  EXCEPTION WHEN OTHERS THEN NULL;
+    DBMS_SQL.CLOSE_CURSOR(c);
+	RAISE;
+  END;
+  :2 := UTL_RAW.CAST_TO_RAW(res);
+END;`, qry, &res,
+	); err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(res, 0); i >= 0 {
+		res = res[:i]
+	}
+	lines := bytes.Split(res, []byte{'\n'})
+	cols := make([]DescribedColumn, 0, len(lines))
+	var nullable int
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		// the schema and the name (which may contain spaces) end with CHR(31)
+		parts := bytes.SplitN(line, []byte{31}, 3)
+		if len(parts) != 3 {
+			continue
+		}
+		col := DescribedColumn{Column: Column{Schema: string(parts[0]), Name: string(parts[1])}}
+		line = parts[2]
+		if n, err := fmt.Sscanf(string(line), "%d %d %d %d %d %d %d",
+			&col.Type, &col.Length, &col.Precision, &col.Scale, &nullable, &col.CharsetID, &col.CharsetForm,
+		); err != nil {
+			return cols, fmt.Errorf("parsing %q (parsed: %d): %v", line, n, err)
+		}
+		col.Nullable = nullable != 0
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
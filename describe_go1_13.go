@@ -0,0 +1,29 @@
+// +build go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DescribeQuery parses the query and returns the column types,
+// with Ses.DescribeQuery on a connection of db.
+func DescribeQuery(db *sql.DB, qry string) ([]DescribedColumn, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var cols []DescribedColumn
+	err = ConnSes(conn, func(ses *Ses) error {
+		cols, err = ses.DescribeQuery(qry)
+		return err
+	})
+	return cols, err
+}
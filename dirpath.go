@@ -0,0 +1,662 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <stdlib.h>
+#include <oci.h>
+
+static sword dpAlloc(OCIDirPathCtx *dpctx, void **hndlpp, ub4 type) {
+	return OCIHandleAlloc(dpctx, hndlpp, type, 0, NULL);
+}
+
+// dpSetColumn describes the pos-th (1-based) column of the direct path context.
+static sword dpSetColumn(OCIDirPathCtx *dpctx, OCIError *errhp, ub4 pos,
+		char *name, ub4 nameLen, ub4 size, char *dateFmt, ub4 dateFmtLen) {
+	OCIParam *collist, *colDesc;
+	ub2 dty = SQLT_CHR;
+	sword r = OCIAttrGet(dpctx, OCI_HTYPE_DIRPATH_CTX, &collist, NULL, OCI_ATTR_LIST_COLUMNS, errhp);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	r = OCIParamGet(collist, OCI_DTYPE_PARAM, errhp, (void **)&colDesc, pos);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	r = OCIAttrSet(colDesc, OCI_DTYPE_PARAM, name, nameLen, OCI_ATTR_NAME, errhp);
+	if (r == OCI_SUCCESS) {
+		r = OCIAttrSet(colDesc, OCI_DTYPE_PARAM, &dty, sizeof(dty), OCI_ATTR_DATA_TYPE, errhp);
+	}
+	if (r == OCI_SUCCESS) {
+		r = OCIAttrSet(colDesc, OCI_DTYPE_PARAM, &size, sizeof(size), OCI_ATTR_DATA_SIZE, errhp);
+	}
+	if (r == OCI_SUCCESS && dateFmtLen > 0) {
+		r = OCIAttrSet(colDesc, OCI_DTYPE_PARAM, dateFmt, dateFmtLen, OCI_ATTR_DATEFORMAT, errhp);
+	}
+	OCIDescriptorFree(colDesc, OCI_DTYPE_PARAM);
+	return r;
+}
+*/
+import "C"
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"gopkg.in/rana/ora.v4/date"
+)
+
+// DirectPathOptions are the options of Ses.DirectPathLoader.
+type DirectPathOptions struct {
+	// Parallel allows parallel loads into the same segment, from other sessions.
+	Parallel bool
+	// NoLogging loads without redo logging.
+	NoLogging bool
+	// BufferSize is the size of the stream buffer in bytes; 0 means the OCI default.
+	BufferSize int
+}
+
+// DirectPathResult is the result of a direct path load.
+type DirectPathResult struct {
+	// Loaded is the number of loaded rows.
+	Loaded int64
+	// Rejected are the rows which couldn't be converted.
+	Rejected []RejectedRow
+}
+
+// RejectedRow is a row rejected by a load.
+type RejectedRow struct {
+	// Row is the 0-based index of the row, counting all the rows of the load.
+	Row int64
+	Err error
+}
+
+// DirectPathLoader loads rows into a table with the direct path API of OCI,
+// bypassing the SQL engine, like SQL*Loader DIRECT=TRUE.
+//
+// The table is locked till Finish or Abort. Triggers and constraints
+// (except NOT NULL) are not checked, and the indexes are rebuilt at Finish.
+type DirectPathLoader struct {
+	ses     *Ses
+	env     *Env
+	columns []dpColumn
+
+	ocidpctx *C.OCIDirPathCtx
+	ocidpca  *C.OCIDirPathColArray
+	ocidpstr *C.OCIDirPathStream
+	maxRows  int // rows of the column array
+
+	rows   int64
+	result DirectPathResult
+	done   bool
+}
+
+type dpKind uint8
+
+const (
+	dpText dpKind = iota
+	dpRaw
+	dpDate
+	dpTimestamp
+	dpTimestampTZ
+)
+
+type dpColumn struct {
+	name string
+	kind dpKind
+	size int
+}
+
+const (
+	dpDateFmt        = "YYYY-MM-DD HH24:MI:SS"
+	dpDateLayout     = "2006-01-02 15:04:05"
+	dpTimestampFmt   = "YYYY-MM-DD HH24:MI:SS.FF9"
+	dpTimestampTZFmt = "YYYY-MM-DD HH24:MI:SS.FF9 TZH:TZM"
+	dpTimestampLay   = "2006-01-02 15:04:05.000000000"
+	dpTimestampTZLay = "2006-01-02 15:04:05.000000000 -07:00"
+)
+
+// DirectPathLoader prepares a direct path load of the columns of the table
+// (as TABLE or SCHEMA.TABLE).
+//
+// Feed the rows with Load or LoadColumns, then call Finish to commit the
+// load, or Abort to discard it.
+func (ses *Ses) DirectPathLoader(table string, columns []string, opts DirectPathOptions) (*DirectPathLoader, error) {
+	if err := ses.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	if len(columns) == 0 {
+		return nil, errNew("no columns to load")
+	}
+	dp := &DirectPathLoader{ses: ses, env: ses.Env()}
+	schema, tbl := splitObjectName(table)
+	var err error
+	if dp.columns, err = ses.describeDirectPath(schema, tbl, columns); err != nil {
+		return nil, err
+	}
+	if err = dp.prepare(schema, tbl, opts); err != nil {
+		dp.free()
+		return nil, err
+	}
+	return dp, nil
+}
+
+// splitObjectName splits SCHEMA.NAME, unquoting or uppercasing the parts.
+func splitObjectName(name string) (schema, object string) {
+	var inQuote bool
+	for i, r := range name {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case '.':
+			if !inQuote {
+				return unquoteName(name[:i]), unquoteName(name[i+1:])
+			}
+		}
+	}
+	return "", unquoteName(name)
+}
+
+func unquoteName(name string) string {
+	if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+		return name[1 : len(name)-1]
+	}
+	return strings.ToUpper(name)
+}
+
+// describeDirectPath returns the description of the columns from the data dictionary.
+func (ses *Ses) describeDirectPath(schema, table string, columns []string) ([]dpColumn, error) {
+	rset, err := ses.PrepAndQry(`SELECT column_name, data_type, data_length, char_length
+  FROM all_tab_columns
+  WHERE owner = NVL(:1, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')) AND table_name = :2`,
+		schema, table)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]dpColumn)
+	for rset.Next() {
+		col := dpColumn{name: rset.Row[0].(string)}
+		typ := rset.Row[1].(string)
+		dataLen, _ := strconv.Atoi(fmt.Sprint(rset.Row[2]))
+		charLen, _ := strconv.Atoi(fmt.Sprint(rset.Row[3]))
+		switch {
+		case typ == "DATE":
+			col.kind, col.size = dpDate, len(dpDateLayout)
+		case strings.HasPrefix(typ, "TIMESTAMP") && strings.Contains(typ, "TIME ZONE"):
+			col.kind, col.size = dpTimestampTZ, len(dpTimestampTZLay)
+		case strings.HasPrefix(typ, "TIMESTAMP"):
+			col.kind, col.size = dpTimestamp, len(dpTimestampLay)
+		case typ == "RAW":
+			col.kind, col.size = dpRaw, 2*dataLen
+		case typ == "BLOB" || typ == "LONG RAW":
+			col.kind, col.size = dpRaw, 1<<24
+		case typ == "CLOB" || typ == "NCLOB" || typ == "LONG":
+			col.size = 1 << 23
+		case charLen > 0:
+			col.size = 4 * charLen
+		default:
+			col.size = 128
+		}
+		types[col.name] = col
+	}
+	if err = rset.Err(); err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, errF("table %q not found", table)
+	}
+	cols := make([]dpColumn, len(columns))
+	for i, name := range columns {
+		col, ok := types[unquoteName(name)]
+		if !ok {
+			return nil, errF("column %q not found in %q", name, table)
+		}
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+// prepare allocates the handles, describes the columns and prepares the load.
+func (dp *DirectPathLoader) prepare(schema, table string, opts DirectPathOptions) error {
+	env := dp.env
+	h, err := env.allocOciHandle(C.OCI_HTYPE_DIRPATH_CTX)
+	if err != nil {
+		return errE(err)
+	}
+	dp.ocidpctx = (*C.OCIDirPathCtx)(h)
+	setAttr := func(p unsafe.Pointer, size C.ub4, attr C.ub4) error {
+		return env.setAttr(unsafe.Pointer(dp.ocidpctx), C.OCI_HTYPE_DIRPATH_CTX, p, size, attr)
+	}
+	setText := func(s string, attr C.ub4) error {
+		cs := C.CString(s)
+		defer C.free(unsafe.Pointer(cs))
+		return setAttr(unsafe.Pointer(cs), C.ub4(len(s)), attr)
+	}
+	if err = setText(table, C.OCI_ATTR_NAME); err != nil {
+		return err
+	}
+	if schema != "" {
+		if err = setText(schema, C.OCI_ATTR_SCHEMA_NAME); err != nil {
+			return err
+		}
+	}
+	numCols := C.ub2(len(dp.columns))
+	if err = setAttr(unsafe.Pointer(&numCols), 2, C.OCI_ATTR_NUM_COLS); err != nil {
+		return err
+	}
+	if opts.BufferSize > 0 {
+		bufSize := C.ub4(opts.BufferSize)
+		if err = setAttr(unsafe.Pointer(&bufSize), 4, C.OCI_ATTR_BUF_SIZE); err != nil {
+			return err
+		}
+	}
+	if opts.Parallel {
+		parallel := C.ub1(1)
+		if err = setAttr(unsafe.Pointer(&parallel), 1, C.OCI_ATTR_DIRPATH_PARALLEL); err != nil {
+			return err
+		}
+	}
+	if opts.NoLogging {
+		nolog := C.ub1(1)
+		if err = setAttr(unsafe.Pointer(&nolog), 1, C.OCI_ATTR_DIRPATH_NOLOG); err != nil {
+			return err
+		}
+	}
+	for i, col := range dp.columns {
+		var dateFmt string
+		switch col.kind {
+		case dpDate:
+			dateFmt = dpDateFmt
+		case dpTimestamp:
+			dateFmt = dpTimestampFmt
+		case dpTimestampTZ:
+			dateFmt = dpTimestampTZFmt
+		}
+		cName, cFmt := C.CString(col.name), C.CString(dateFmt)
+		env.RLock()
+		r := C.dpSetColumn(dp.ocidpctx, env.ocierr, C.ub4(i+1),
+			cName, C.ub4(len(col.name)), C.ub4(col.size), cFmt, C.ub4(len(dateFmt)))
+		env.RUnlock()
+		C.free(unsafe.Pointer(cName))
+		C.free(unsafe.Pointer(cFmt))
+		if r == C.OCI_ERROR {
+			return env.ociError()
+		}
+	}
+
+	dp.ses.RLock()
+	env.RLock()
+	r := C.OCIDirPathPrepare(dp.ocidpctx, dp.ses.ocisvcctx, env.ocierr)
+	env.RUnlock()
+	dp.ses.RUnlock()
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	var p unsafe.Pointer
+	if C.dpAlloc(dp.ocidpctx, &p, C.OCI_HTYPE_DIRPATH_COLUMN_ARRAY) != C.OCI_SUCCESS {
+		return er("Unable to allocate direct path column array handle")
+	}
+	dp.ocidpca = (*C.OCIDirPathColArray)(p)
+	if C.dpAlloc(dp.ocidpctx, &p, C.OCI_HTYPE_DIRPATH_STREAM) != C.OCI_SUCCESS {
+		return er("Unable to allocate direct path stream handle")
+	}
+	dp.ocidpstr = (*C.OCIDirPathStream)(p)
+	maxRows, err := dp.caAttr(C.OCI_ATTR_NUM_ROWS)
+	if err != nil {
+		return err
+	}
+	dp.maxRows = int(maxRows)
+	return nil
+}
+
+// Load loads the rows, each having a value for each column.
+//
+// The values may be nil, the nullable types of this package (String, Int64,
+// Time, Date ...), pointers, strings, []byte (for RAW and BLOB columns),
+// time.Time, bools, numbers and fmt.Stringers.
+//
+// The rows which can't be converted are rejected, and returned by Finish.
+func (dp *DirectPathLoader) Load(rows ...[]interface{}) error {
+	return dp.load(len(rows), func(i, j int) interface{} { return rows[i][j] },
+		func(i int) error {
+			if len(rows[i]) != len(dp.columns) {
+				return errF("row %d has %d values, wanted %d", i, len(rows[i]), len(dp.columns))
+			}
+			return nil
+		})
+}
+
+// LoadColumns loads the rows given as column-major slices: one slice
+// (such as []string, []int64, []ora.Time or []interface{}) for each column.
+// The slices must have the same length.
+func (dp *DirectPathLoader) LoadColumns(columns ...interface{}) error {
+	if len(columns) != len(dp.columns) {
+		return errF("got %d columns, wanted %d", len(columns), len(dp.columns))
+	}
+	values := make([]reflect.Value, len(columns))
+	n := -1
+	for j, col := range columns {
+		values[j] = reflect.ValueOf(col)
+		if values[j].Kind() != reflect.Slice {
+			return errF("column %d is %T, not a slice", j, col)
+		}
+		if n < 0 {
+			n = values[j].Len()
+		} else if values[j].Len() != n {
+			return errF("column %d has %d rows, wanted %d", j, values[j].Len(), n)
+		}
+	}
+	return dp.load(n, func(i, j int) interface{} { return values[j].Index(i).Interface() }, nil)
+}
+
+// load loads n rows in column array sized batches.
+func (dp *DirectPathLoader) load(n int, value func(i, j int) interface{}, check func(i int) error) error {
+	if dp.done {
+		return errNew("DirectPathLoader is finished")
+	}
+	for i := 0; i < n; {
+		batch := n - i
+		if batch > dp.maxRows {
+			batch = dp.maxRows
+		}
+		if err := dp.loadBatch(i, batch, value, check); err != nil {
+			return err
+		}
+		i += batch
+	}
+	return nil
+}
+
+// loadBatch sets the column array from the values of rows [start, start+batch),
+// and loads them.
+func (dp *DirectPathLoader) loadBatch(start, batch int, value func(i, j int) interface{}, check func(i int) error) error {
+	env := dp.env
+	var arena []byte
+	type entry struct {
+		off, len int
+		null     bool
+	}
+	entries := make([]entry, 0, batch*len(dp.columns))
+	rowNums := make([]int64, 0, batch) // load-wide row numbers of the column array rows
+Rows:
+	for i := start; i < start+batch; i++ {
+		rowNum := dp.rows + int64(i-start)
+		if check != nil {
+			if err := check(i); err != nil {
+				dp.reject(rowNum, err)
+				continue
+			}
+		}
+		mark := len(entries)
+		for j := range dp.columns {
+			b, null, err := dp.columns[j].format(value(i, j))
+			if err != nil {
+				dp.reject(rowNum, errF("column %s: %v", dp.columns[j].name, err))
+				entries = entries[:mark]
+				continue Rows
+			}
+			entries = append(entries, entry{off: len(arena), len: len(b), null: null})
+			arena = append(arena, b...)
+		}
+		rowNums = append(rowNums, rowNum)
+	}
+	dp.rows += int64(batch)
+	if len(rowNums) == 0 {
+		return nil
+	}
+
+	// The column array points into the arena till the conversion to stream.
+	var cArena unsafe.Pointer
+	if len(arena) > 0 {
+		cArena = C.CBytes(arena)
+		defer C.free(cArena)
+	}
+	ncols := len(dp.columns)
+	env.RLock()
+	for k, e := range entries {
+		flag := C.ub1(C.OCI_DIRPATH_COL_COMPLETE)
+		var p *C.ub1
+		if e.null {
+			flag = C.OCI_DIRPATH_COL_NULL
+		} else if e.len > 0 {
+			p = (*C.ub1)(unsafe.Pointer(uintptr(cArena) + uintptr(e.off)))
+		}
+		if r := C.OCIDirPathColArrayEntrySet(dp.ocidpca, env.ocierr,
+			C.ub4(k/ncols), C.ub2(k%ncols), p, C.ub4(e.len), flag); r == C.OCI_ERROR {
+			env.RUnlock()
+			return env.ociError()
+		}
+	}
+	env.RUnlock()
+
+	defer func() {
+		env.RLock()
+		C.OCIDirPathColArrayReset(dp.ocidpca, env.ocierr)
+		env.RUnlock()
+	}()
+	rowCount := len(rowNums)
+	for off := 0; off < rowCount; {
+		env.RLock()
+		r := C.OCIDirPathColArrayToStream(dp.ocidpca, dp.ocidpctx, dp.ocidpstr, env.ocierr,
+			C.ub4(rowCount), C.ub4(off))
+		env.RUnlock()
+		var convErr error
+		if r == C.OCI_ERROR {
+			convErr = env.ociError()
+		}
+		converted, err := dp.caAttr(C.OCI_ATTR_ROW_COUNT)
+		if err != nil {
+			return err
+		}
+		if err = dp.loadStream(); err != nil {
+			return err
+		}
+		switch r {
+		case C.OCI_SUCCESS:
+			dp.result.Loaded += int64(converted)
+			off = rowCount
+		case C.OCI_CONTINUE: // the stream is full
+			dp.result.Loaded += int64(converted)
+			off += int(converted)
+		case C.OCI_ERROR: // the row after the converted ones is bad
+			dp.result.Loaded += int64(converted)
+			off += int(converted)
+			if off < rowCount {
+				dp.reject(rowNums[off], convErr)
+			}
+			off++
+		default:
+			return errF("OCIDirPathColArrayToStream returned %d", r)
+		}
+	}
+	return nil
+}
+
+// loadStream loads the converted stream, and resets it.
+func (dp *DirectPathLoader) loadStream() error {
+	env := dp.env
+	env.RLock()
+	defer env.RUnlock()
+	r := C.OCIDirPathLoadStream(dp.ocidpctx, dp.ocidpstr, env.ocierr)
+	C.OCIDirPathStreamReset(dp.ocidpstr, env.ocierr)
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	return nil
+}
+
+// caAttr returns an ub4 attribute of the column array.
+func (dp *DirectPathLoader) caAttr(attr C.ub4) (C.ub4, error) {
+	var v C.ub4
+	env := dp.env
+	env.RLock()
+	r := C.OCIAttrGet(unsafe.Pointer(dp.ocidpca), C.OCI_HTYPE_DIRPATH_COLUMN_ARRAY,
+		unsafe.Pointer(&v), nil, attr, env.ocierr)
+	env.RUnlock()
+	if r == C.OCI_ERROR {
+		return 0, env.ociError()
+	}
+	return v, nil
+}
+
+func (dp *DirectPathLoader) reject(row int64, err error) {
+	dp.result.Rejected = append(dp.result.Rejected, RejectedRow{Row: row, Err: err})
+}
+
+// Finish finishes the load (saving the data and rebuilding the indexes),
+// and returns the number of loaded rows and the rejected rows.
+func (dp *DirectPathLoader) Finish() (DirectPathResult, error) {
+	if dp.done {
+		return dp.result, errNew("DirectPathLoader is finished")
+	}
+	dp.done = true
+	defer dp.free()
+	env := dp.env
+	env.RLock()
+	r := C.OCIDirPathFinish(dp.ocidpctx, env.ocierr)
+	env.RUnlock()
+	if r == C.OCI_ERROR {
+		return dp.result, env.ociError()
+	}
+	return dp.result, nil
+}
+
+// Abort discards the load.
+func (dp *DirectPathLoader) Abort() error {
+	if dp.done {
+		return nil
+	}
+	dp.done = true
+	defer dp.free()
+	env := dp.env
+	env.RLock()
+	r := C.OCIDirPathAbort(dp.ocidpctx, env.ocierr)
+	env.RUnlock()
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	return nil
+}
+
+// free frees the OCI handles.
+func (dp *DirectPathLoader) free() {
+	if dp.ocidpstr != nil {
+		C.OCIHandleFree(unsafe.Pointer(dp.ocidpstr), C.OCI_HTYPE_DIRPATH_STREAM)
+		dp.ocidpstr = nil
+	}
+	if dp.ocidpca != nil {
+		C.OCIHandleFree(unsafe.Pointer(dp.ocidpca), C.OCI_HTYPE_DIRPATH_COLUMN_ARRAY)
+		dp.ocidpca = nil
+	}
+	if dp.ocidpctx != nil {
+		dp.env.freeOciHandle(unsafe.Pointer(dp.ocidpctx), C.OCI_HTYPE_DIRPATH_CTX)
+		dp.ocidpctx = nil
+	}
+}
+
+// format returns the text of v to be loaded into the column.
+func (col dpColumn) format(v interface{}) (b []byte, null bool, err error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, true, nil
+	case string:
+		b = []byte(x)
+	case []byte:
+		if x == nil {
+			return nil, true, nil
+		}
+		if col.kind == dpRaw {
+			b = make([]byte, hex.EncodedLen(len(x)))
+			hex.Encode(b, x)
+		} else {
+			b = x
+		}
+	case time.Time:
+		if x.IsZero() {
+			return nil, true, nil
+		}
+		switch col.kind {
+		case dpDate:
+			b = []byte(x.Format(dpDateLayout))
+		case dpTimestamp:
+			b = []byte(x.Format(dpTimestampLay))
+		case dpTimestampTZ:
+			b = []byte(x.Format(dpTimestampTZLay))
+		default:
+			return nil, false, errF("time for a non-date column")
+		}
+	case Date:
+		if x.IsNull() {
+			return nil, true, nil
+		}
+		return col.format(x.Get())
+	case date.Date:
+		if x.IsNull() {
+			return nil, true, nil
+		}
+		return col.format(x.Get())
+	case bool:
+		if x {
+			b = []byte{'1'}
+		} else {
+			b = []byte{'0'}
+		}
+	case int:
+		b = strconv.AppendInt(nil, int64(x), 10)
+	case int64:
+		b = strconv.AppendInt(nil, x, 10)
+	case int32:
+		b = strconv.AppendInt(nil, int64(x), 10)
+	case uint64:
+		b = strconv.AppendUint(nil, x, 10)
+	case float64:
+		b = strconv.AppendFloat(nil, x, 'g', -1, 64)
+	case float32:
+		b = strconv.AppendFloat(nil, float64(x), 'g', -1, 32)
+	default:
+		rv := reflect.ValueOf(v)
+		if s, ok := v.(fmt.Stringer); ok && rv.Kind() != reflect.Struct {
+			b = []byte(s.String())
+			break
+		}
+		switch rv.Kind() {
+		case reflect.Ptr:
+			if rv.IsNil() {
+				return nil, true, nil
+			}
+			return col.format(rv.Elem().Interface())
+		case reflect.Struct: // the nullable types: IsNull and Value
+			isNull, value := rv.FieldByName("IsNull"), rv.FieldByName("Value")
+			if isNull.Kind() != reflect.Bool || !value.IsValid() {
+				if s, ok := v.(fmt.Stringer); ok {
+					b = []byte(s.String())
+					break
+				}
+				return nil, false, errF("unsupported type %T", v)
+			}
+			if isNull.Bool() {
+				return nil, true, nil
+			}
+			return col.format(value.Interface())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			b = strconv.AppendInt(nil, rv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			b = strconv.AppendUint(nil, rv.Uint(), 10)
+		case reflect.String:
+			b = []byte(rv.String())
+		default:
+			return nil, false, errF("unsupported type %T", v)
+		}
+	}
+	if len(b) > col.size {
+		return nil, false, errF("value is too long (%d > %d)", len(b), col.size)
+	}
+	return b, false, nil
+}
@@ -20,6 +20,15 @@ import (
 type DrvCfg struct {
 	StmtCfg
 	Log LogDrvCfg
+
+	// Events enables the OCI_EVENTS mode for the new Envs, to receive the
+	// HA (FAN) notifications: the sessions to an instance reported down
+	// are dropped by the pools right away.
+	//
+	// The service must be configured with AQ_HA_NOTIFICATIONS.
+	//
+	// Events is needed by Ses.Subscribe, too.
+	Events bool
 }
 
 // NewDrvCfg creates a DrvCfg with default values.
@@ -60,6 +69,10 @@ func (c DrvCfg) SetByteSlice(gct GoColumnType) DrvCfg {
 	c.StmtCfg = c.StmtCfg.SetByteSlice(gct)
 	return c
 }
+func (c DrvCfg) SetCallTimeout(d time.Duration) DrvCfg {
+	c.StmtCfg = c.StmtCfg.SetCallTimeout(d)
+	return c
+}
 func (c DrvCfg) SetNumberInt(gct GoColumnType) DrvCfg {
 	c.StmtCfg = c.StmtCfg.SetNumberInt(gct)
 	return c
@@ -107,7 +120,8 @@ func (c DrvCfg) SetBlob(gct GoColumnType) DrvCfg    { c.StmtCfg = c.StmtCfg.SetB
 func (c DrvCfg) SetRaw(gct GoColumnType) DrvCfg     { c.StmtCfg = c.StmtCfg.SetRaw(gct); return c }
 func (c DrvCfg) SetLongRaw(gct GoColumnType) DrvCfg { c.StmtCfg = c.StmtCfg.SetLongRaw(gct); return c }
 
-func (c DrvCfg) SetLogger(lgr Logger) DrvCfg { c.Log.Logger = lgr; return c }
+func (c DrvCfg) SetLogger(lgr Logger) DrvCfg  { c.Log.Logger = lgr; return c }
+func (c DrvCfg) SetEvents(events bool) DrvCfg { c.Events = events; return c }
 
 // LogDrvCfg represents package-level logging configuration values.
 type LogDrvCfg struct {
@@ -208,6 +222,7 @@ type Drv struct {
 	txId   Id
 	stmtId Id
 	rsetId Id
+	poolId Id
 
 	listPool *sync.Pool
 	envPool  *sync.Pool
@@ -5,24 +5,22 @@
 package ora
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"io"
 	"reflect"
-	"strconv"
 	"time"
 )
 
-/*
-#include <oci.h>
-*/
-import "C"
-
 // DrvQueryResult contains methods to retrieve the results of a SQL select statement.
 //
 // DrvQueryResult implements the driver.Rows interface.
 type DrvQueryResult struct {
 	rset *Rset
+	// ctx is the context of the query, checked before each fetch.
+	ctx context.Context
 }
 
 // Next populates the specified slice with the next row of data.
@@ -34,7 +32,16 @@ func (qr *DrvQueryResult) Next(dest []driver.Value) (err error) {
 	if qr.rset == nil {
 		return er("empty Rset")
 	}
-	err = qr.rset.beginRow()
+	if qr.ctx != nil && qr.rset.needFetch() {
+		// Break the fetch when the context is done.
+		err = runCtx(qr.ctx, qr.rset.stmt.getSes(), qr.rset.beginRow)
+		if ctxErr := qr.ctx.Err(); ctxErr != nil {
+			qr.cancel()
+			return ctxErr
+		}
+	} else {
+		err = qr.rset.beginRow()
+	}
 	if err != nil {
 		// FIXME(tgulacsi): this results in erroneous short iteration!
 		qr.rset.close()
@@ -91,80 +98,10 @@ func (qr *DrvQueryResult) ColumnTypeDatabaseTypeName(index int) string {
 	if qr.rset == nil {
 		return ""
 	}
-	// https://docs.oracle.com/cd/E11882_01/appdev.112/e10646/oci03typ.htm#LNOCI16271
 	qr.rset.RLock()
 	x := qr.rset.Columns[index].Type
 	qr.rset.RUnlock()
-	switch x {
-	case C.SQLT_CHR:
-		return "VARCHAR2"
-	case C.SQLT_NUM:
-		return "NUMBER"
-	case C.SQLT_INT:
-		return "INTEGER"
-	case C.SQLT_FLT:
-		return "FLOAT"
-	case C.SQLT_STR:
-		return "STRING"
-	case C.SQLT_VNU:
-		return "VARNUM"
-	case C.SQLT_LNG:
-		return "LONG"
-	case C.SQLT_VCS:
-		return "VARCHAR"
-	case C.SQLT_DAT:
-		return "DATE"
-	case C.SQLT_VBI:
-		return "VARRAW"
-	case C.SQLT_BFLOAT:
-		return "NATIVE FLOAT"
-	case C.SQLT_BDOUBLE:
-		return "NATIVE DOUBLE"
-	case C.SQLT_BIN:
-		return "RAW"
-	case C.SQLT_LBI:
-		return "LONG RAW"
-	case C.SQLT_UIN:
-		return "UNSIGNED INT"
-	case C.SQLT_LVC:
-		return "LONG VARCHAR"
-	case C.SQLT_LVB:
-		return "LONG VARRAW"
-	case C.SQLT_AFC:
-		return "CHAR"
-	case C.SQLT_AVC:
-		return "CHARZ"
-	case C.SQLT_RDD:
-		return "ROWID"
-	case C.SQLT_NTY:
-		return "NAMED"
-	case C.SQLT_REF:
-		return "REF"
-	case C.SQLT_CLOB:
-		return "CLOB"
-	case C.SQLT_BLOB:
-		return "BLOB"
-	case C.SQLT_FILE:
-		return "FILE"
-	case C.SQLT_VST:
-		return "OCI STRING"
-	case C.SQLT_ODT:
-		return "OCI DATE"
-	case C.SQLT_DATE:
-		return "ANSI DATE"
-	case C.SQLT_TIMESTAMP:
-		return "TIMESTAMP"
-	case C.SQLT_TIMESTAMP_TZ:
-		return "TIMESTAMP WITH TIME ZONE"
-	case C.SQLT_INTERVAL_YM:
-		return "INTERVAL YEAR TO MONTH"
-	case C.SQLT_INTERVAL_DS:
-		return "INTERVAL DAY TO SECOND"
-	case C.SQLT_TIMESTAMP_LTZ:
-		return "TIMESTAMP WITH LOCAL TIME ZONE"
-	default:
-		return strconv.Itoa(int(x))
-	}
+	return x.String()
 }
 
 // ColumnTypeLength returns the length of the column type
@@ -189,7 +126,13 @@ func (qr *DrvQueryResult) ColumnTypeLength(index int) (length int64, ok bool) {
 // or false if the column is known to be not nullable.
 // If the column nullability is unknown, ok should be false.
 func (qr *DrvQueryResult) ColumnTypeNullable(index int) (nullable, ok bool) {
-	return true, true
+	if qr.rset == nil {
+		return false, false
+	}
+	qr.rset.RLock()
+	nullable = qr.rset.Columns[index].Nullable
+	qr.rset.RUnlock()
+	return nullable, true
 }
 
 // ColumnTypePrecisionScale return the precision and scale for decimal types.
@@ -201,50 +144,139 @@ func (qr *DrvQueryResult) ColumnTypePrecisionScale(index int) (precision, scale
 	qr.rset.RLock()
 	c := qr.rset.Columns[index]
 	qr.rset.RUnlock()
-	if c.Type == C.SQLT_NUM || c.Type == C.SQLT_INT {
+	if c.Type == TypeNumber || c.Type == TypeInteger {
 		return int64(c.Precision), int64(c.Scale), true
 	}
 	return 0, 0, false
 }
 
+// ColumnTypeScanType returns the type of the values of the column.
+//
+// For nullable columns, it is sql.NullInt64, sql.NullFloat64, sql.NullString,
+// sql.NullBool or sql.NullTime (*time.Time before Go 1.13) if the values are
+// returned as plain Go types, and a pointer for the types that can't hold NULL,
+// so a value of it can always be scanned into.
 func (qr *DrvQueryResult) ColumnTypeScanType(index int) reflect.Type {
 	if qr.rset == nil {
 		return nil
 	}
 	qr.rset.RLock()
-	x := qr.rset.Columns[index].Type
+	c := qr.rset.Columns[index]
 	qr.rset.RUnlock()
-	switch x {
-	case C.SQLT_CHR, C.SQLT_STR, C.SQLT_LNG, C.SQLT_VCS, C.SQLT_LVC, C.SQLT_AFC, C.SQLT_AVC, C.SQLT_CLOB, C.SQLT_VST:
-		return reflect.TypeOf("")
-	case C.SQLT_NUM, C.SQLT_FLT, C.SQLT_VNU, C.SQLT_BDOUBLE:
-		return reflect.TypeOf(float64(0))
-	case C.SQLT_INT:
-		return reflect.TypeOf(int64(0))
-	case C.SQLT_DAT, C.SQLT_ODT, C.SQLT_DATE, C.SQLT_TIMESTAMP, C.SQLT_TIMESTAMP_TZ, C.SQLT_TIMESTAMP_LTZ:
-		return reflect.TypeOf(time.Time{})
-	case C.SQLT_VBI, C.SQLT_BIN, C.SQLT_LBI, C.SQLT_LVB, C.SQLT_RDD, C.SQLT_BLOB, C.SQLT_FILE:
-		return reflect.TypeOf([]byte{})
-	case C.SQLT_BFLOAT:
-		return reflect.TypeOf(float32(0))
-	case C.SQLT_UIN:
-		return reflect.TypeOf(uint64(0))
-
-	case C.SQLT_NTY, C.SQLT_REF:
-		return reflect.TypeOf([]byte{})
-	case C.SQLT_INTERVAL_YM, C.SQLT_INTERVAL_DS:
-		return reflect.TypeOf(time.Duration(0))
-
-	default:
-		var x interface{}
-		return reflect.TypeOf(x)
+	return scanType(c)
+}
+
+var (
+	// gctTypes are the types of the values of the GoColumnTypes.
+	gctTypes = map[GoColumnType]reflect.Type{
+		I64:    reflect.TypeOf(int64(0)),
+		I32:    reflect.TypeOf(int32(0)),
+		I16:    reflect.TypeOf(int16(0)),
+		I8:     reflect.TypeOf(int8(0)),
+		U64:    reflect.TypeOf(uint64(0)),
+		U32:    reflect.TypeOf(uint32(0)),
+		U16:    reflect.TypeOf(uint16(0)),
+		U8:     reflect.TypeOf(uint8(0)),
+		F64:    reflect.TypeOf(float64(0)),
+		F32:    reflect.TypeOf(float32(0)),
+		OraI64: reflect.TypeOf(Int64{}),
+		OraI32: reflect.TypeOf(Int32{}),
+		OraI16: reflect.TypeOf(Int16{}),
+		OraI8:  reflect.TypeOf(Int8{}),
+		OraU64: reflect.TypeOf(Uint64{}),
+		OraU32: reflect.TypeOf(Uint32{}),
+		OraU16: reflect.TypeOf(Uint16{}),
+		OraU8:  reflect.TypeOf(Uint8{}),
+		OraF64: reflect.TypeOf(Float64{}),
+		OraF32: reflect.TypeOf(Float32{}),
+		T:      reflect.TypeOf(time.Time{}),
+		OraT:   reflect.TypeOf(Time{}),
+		S:      reflect.TypeOf(""),
+		OraS:   reflect.TypeOf(String{}),
+		B:      reflect.TypeOf(false),
+		OraB:   reflect.TypeOf(Bool{}),
+		Bin:    reflect.TypeOf([]byte(nil)),
+		OraBin: reflect.TypeOf(Raw{}),
+		N:      reflect.TypeOf(OCINum{}),
+		OraN:   reflect.TypeOf(OraOCINum{}),
+		L:      reflect.TypeOf((*Lob)(nil)),
 	}
 
+	// gctNullTypes are the types of the nullable columns of
+	// the GoColumnTypes returning nil for NULL.
+	gctNullTypes = map[GoColumnType]reflect.Type{
+		I64: reflect.TypeOf(sql.NullInt64{}),
+		I32: reflect.TypeOf(sql.NullInt64{}),
+		I16: reflect.TypeOf(sql.NullInt64{}),
+		I8:  reflect.TypeOf(sql.NullInt64{}),
+		U64: reflect.TypeOf(sql.NullInt64{}),
+		U32: reflect.TypeOf(sql.NullInt64{}),
+		U16: reflect.TypeOf(sql.NullInt64{}),
+		U8:  reflect.TypeOf(sql.NullInt64{}),
+		F64: reflect.TypeOf(sql.NullFloat64{}),
+		F32: reflect.TypeOf(sql.NullFloat64{}),
+		T:   nullTimeType,
+		S:   reflect.TypeOf(sql.NullString{}),
+		B:   reflect.TypeOf(sql.NullBool{}),
+	}
+)
+
+// scanType returns the type of the values of the column, see ColumnTypeScanType.
+func scanType(c Column) reflect.Type {
+	t, ok := gctTypes[c.GoType]
+	if !ok {
+		switch c.Type {
+		case TypeIntervalYM:
+			t = reflect.TypeOf(IntervalYM{})
+		case TypeIntervalDS:
+			t = reflect.TypeOf(IntervalDS{})
+		case TypeBfile:
+			t = reflect.TypeOf(Bfile{})
+		case TypeRowid:
+			// NULL is returned as ""
+			return reflect.TypeOf("")
+		case TypeCursor:
+			t = reflect.TypeOf((*Rset)(nil))
+		default:
+			var x interface{}
+			return reflect.TypeOf(&x).Elem()
+		}
+	}
+	if !c.Nullable {
+		return t
+	}
+	if nt, ok := gctNullTypes[c.GoType]; ok {
+		return nt
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return t
+	case reflect.Struct:
+		// the Ora types have an IsNull field
+		if _, ok := t.FieldByName("IsNull"); ok {
+			return t
+		}
+	}
+	return reflect.PtrTo(t)
 }
 
-// Close performs no operations.
+// Close cancels the cursor on the server if the context of the query is done;
+// otherwise performs no operations.
 //
 // Close is a member of the driver.Rows interface.
 func (qr *DrvQueryResult) Close() error {
+	if qr.rset != nil && qr.ctx != nil && qr.ctx.Err() != nil {
+		qr.cancel()
+	}
 	return nil
 }
+
+// cancel cancels the cursor on the server, and closes the Rset.
+func (qr *DrvQueryResult) cancel() {
+	if qr.rset == nil {
+		return
+	}
+	qr.rset.cancel()
+	qr.rset.closeWithRemove()
+	qr.rset = nil
+}
@@ -0,0 +1,15 @@
+// +build !go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"reflect"
+	"time"
+)
+
+// nullTimeType is the scan type of nullable time columns.
+var nullTimeType = reflect.TypeOf((*time.Time)(nil))
@@ -90,7 +90,7 @@ func (ds *DrvStmt) QueryContext(ctx context.Context, values []driver.NamedValue)
 	}()
 	select {
 	case err := <-done:
-		return &DrvQueryResult{rset: rset}, err
+		return &DrvQueryResult{rset: rset, ctx: ctx}, err
 	case <-ctx.Done():
 		err := ctx.Err()
 		if isCanceled(err) {
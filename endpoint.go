@@ -0,0 +1,158 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEndpointCooldown is the time an endpoint is avoided after a failed connection.
+const DefaultEndpointCooldown = 30 * time.Second
+
+// Endpoint is a database server (primary, standby, RAC node) a Pool may connect to.
+type Endpoint struct {
+	// Dblink is the connect string of the server, as in SrvCfg.Dblink.
+	Dblink string
+	// Weight is the relative weight for RoundRobin; zero means 1.
+	Weight int
+}
+
+// EndpointStrategy specifies how a Pool chooses between its endpoints.
+type EndpointStrategy uint8
+
+const (
+	// FailoverInOrder connects to the first available endpoint, in the given order.
+	FailoverInOrder EndpointStrategy = iota
+	// RoundRobin distributes the new connections between the endpoints, by their weight.
+	RoundRobin
+	// LeastInUse connects to the endpoint with the least sessions in use.
+	LeastInUse
+)
+
+// PoolEndpoints makes the Pool connect to the given endpoints instead of SrvCfg.Dblink,
+// choosing between them by the strategy.
+//
+// Endpoints failing to connect are avoided for the cooldown duration
+// (see PoolEndpointCooldown), but tried as the last resort.
+func PoolEndpoints(strategy EndpointStrategy, endpoints ...Endpoint) PoolOption {
+	return func(o *poolOption) {
+		o.strategy = strategy
+		o.endpoints = append(o.endpoints[:0], endpoints...)
+	}
+}
+
+// PoolEndpointCooldown sets the duration an endpoint failing to connect is avoided.
+//
+// Defaults to DefaultEndpointCooldown.
+func PoolEndpointCooldown(d time.Duration) PoolOption {
+	return func(o *poolOption) { o.cooldown = d }
+}
+
+type endpoint struct {
+	inUse    int64 // atomic
+	failedAt int64 // atomic, UnixNano
+	Endpoint
+}
+
+func newEndpoints(eps []Endpoint) []*endpoint {
+	if len(eps) == 0 {
+		return nil
+	}
+	res := make([]*endpoint, len(eps))
+	for i, ep := range eps {
+		if ep.Weight <= 0 {
+			ep.Weight = 1
+		}
+		res[i] = &endpoint{Endpoint: ep}
+	}
+	return res
+}
+
+type byInUse []*endpoint
+
+func (a byInUse) Len() int      { return len(a) }
+func (a byInUse) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byInUse) Less(i, j int) bool {
+	return atomic.LoadInt64(&a[i].inUse) < atomic.LoadInt64(&a[j].inUse)
+}
+
+func (ep *endpoint) coolingDown(now time.Time, cooldown time.Duration) bool {
+	t := atomic.LoadInt64(&ep.failedAt)
+	return t != 0 && now.Sub(time.Unix(0, t)) < cooldown
+}
+
+// endpointsInOrder returns the endpoints in the order they should be tried.
+func (p *Pool) endpointsInOrder(now time.Time) []*endpoint {
+	eps := make([]*endpoint, len(p.endpoints))
+	copy(eps, p.endpoints)
+	switch p.opt.strategy {
+	case RoundRobin:
+		var total int
+		for _, ep := range eps {
+			total += ep.Weight
+		}
+		k := int(atomic.AddUint64(&p.rr, 1) % uint64(total))
+		var first int
+		for i, ep := range eps {
+			if k -= ep.Weight; k < 0 {
+				first = i
+				break
+			}
+		}
+		eps = append(eps[first:], eps[:first]...)
+	case LeastInUse:
+		sort.Stable(byInUse(eps))
+	}
+	// the cooling down endpoints are the last resort
+	ordered := eps[:0:0]
+	var cooling []*endpoint
+	for _, ep := range eps {
+		if ep.coolingDown(now, p.opt.cooldown) {
+			cooling = append(cooling, ep)
+		} else {
+			ordered = append(ordered, ep)
+		}
+	}
+	return append(ordered, cooling...)
+}
+
+// openSrv opens a new connection, to one of the endpoints, if set.
+func (p *Pool) openSrv() (*Srv, error) {
+	if len(p.endpoints) == 0 {
+		return p.env.OpenSrv(p.srvCfg)
+	}
+	now := time.Now()
+	var err error
+	for _, ep := range p.endpointsInOrder(now) {
+		cfg := p.srvCfg
+		cfg.Dblink = ep.Dblink
+		var srv *Srv
+		if srv, err = p.env.OpenSrv(cfg); err == nil {
+			atomic.StoreInt64(&ep.failedAt, 0)
+			return srv, nil
+		}
+		atomic.StoreInt64(&ep.failedAt, now.UnixNano())
+		_drv.Cfg().Log.Logger.Errorf("Pool connect to %q: %v", ep.Dblink, err)
+	}
+	return nil, err
+}
+
+// endpointAdd adds d to the in use count of the session's endpoint.
+func (p *Pool) endpointAdd(ses *Ses, d int64) {
+	if len(p.endpoints) == 0 {
+		return
+	}
+	dblink := ses.Endpoint()
+	for _, ep := range p.endpoints {
+		if ep.Dblink == dblink {
+			if atomic.AddInt64(&ep.inUse, d) < 0 {
+				atomic.AddInt64(&ep.inUse, -d)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEndpointsInOrder tests Pool.endpointsInOrder.
+func TestEndpointsInOrder(t *testing.T) {
+	now := time.Now()
+	order := func(p *Pool) string {
+		eps := p.endpointsInOrder(now)
+		names := make([]string, len(eps))
+		for i, ep := range eps {
+			names[i] = ep.Dblink
+		}
+		return strings.Join(names, ",")
+	}
+	newPool := func(strategy EndpointStrategy, eps ...Endpoint) *Pool {
+		p := &Pool{opt: poolOption{strategy: strategy, cooldown: time.Minute}}
+		p.endpoints = newEndpoints(eps)
+		return p
+	}
+
+	p := newPool(FailoverInOrder, Endpoint{Dblink: "a"}, Endpoint{Dblink: "b"}, Endpoint{Dblink: "c"})
+	if got, want := order(p), "a,b,c"; got != want {
+		t.Errorf("in order: got %q, wanted %q.", got, want)
+	}
+	p.endpoints[0].failedAt = now.Add(-time.Second).UnixNano()
+	if got, want := order(p), "b,c,a"; got != want {
+		t.Errorf("cooldown: got %q, wanted %q.", got, want)
+	}
+	p.endpoints[0].failedAt = now.Add(-2 * time.Minute).UnixNano()
+	if got, want := order(p), "a,b,c"; got != want {
+		t.Errorf("after cooldown: got %q, wanted %q.", got, want)
+	}
+
+	p = newPool(RoundRobin, Endpoint{Dblink: "a", Weight: 2}, Endpoint{Dblink: "b"})
+	firsts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		firsts[order(p)[:1]]++
+	}
+	if firsts["a"] != 20 || firsts["b"] != 10 {
+		t.Errorf("round robin: got %v, wanted a:20 b:10.", firsts)
+	}
+
+	p = newPool(LeastInUse, Endpoint{Dblink: "a"}, Endpoint{Dblink: "b"}, Endpoint{Dblink: "c"})
+	p.endpoints[0].inUse, p.endpoints[1].inUse = 3, 1
+	if got, want := order(p), "c,b,a"; got != want {
+		t.Errorf("least in use: got %q, wanted %q.", got, want)
+	}
+}
@@ -55,9 +55,10 @@ type Env struct {
 	cfg      atomic.Value
 	ocienv   *C.OCIEnv
 	ocierr   *C.OCIError
-	errBuf   [512]C.char
+	errBuf   [3072]C.char
 	ociHndMu sync.Mutex
 	isPkgEnv bool
+	events   bool // opened in OCI_EVENTS mode
 
 	openSrvs *srvList
 	openCons *conList
@@ -97,9 +98,11 @@ func (env *Env) Close() (err error) {
 			errs.PushBack(errR(value))
 		}
 		_drv.openEnvs.remove(env)
+		eventEnvs.remove(env.id)
 		env.SetCfg(StmtCfg{})
 		env.Lock()
 		env.isPkgEnv = false
+		env.events = false
 		env.ocienv = nil
 		env.ocierr = nil
 		env.Unlock()
@@ -272,6 +275,12 @@ func (env *Env) OpenSrv(cfg SrvCfg) (srv *Srv, err error) {
 	env.RLock()
 	env.openSrvs.add(srv)
 	env.RUnlock()
+	if cfg.OnFailover != nil && cfg.Pool.Type == NoPool {
+		if err := srv.setFailover(env, srv.ocisrv); err != nil {
+			srv.closeWithRemove()
+			return nil, errE(err)
+		}
+	}
 
 	return srv, nil
 }
@@ -497,48 +506,40 @@ func (env *Env) setAttr(
 	return nil
 }
 
-// ociError gets an error returned by an Oracle server.
+// ociError gets an error returned by an Oracle server,
+// with all the records of the error stack.
 func (env *Env) ociError(prefix ...string) error {
 	var errcode C.sb4
+	var records []ErrorRecord
 	env.RLock()
-	C.OCIErrorGet(
-		unsafe.Pointer(env.ocierr),
-		1, nil,
-		&errcode,
-		(*C.OraText)(unsafe.Pointer(&env.errBuf[0])),
-		C.ub4(len(env.errBuf)),
-		C.OCI_HTYPE_ERROR)
-	msg := C.GoString(&env.errBuf[0])
+	for recordno := C.ub4(1); ; recordno++ {
+		if r := C.OCIErrorGet(
+			unsafe.Pointer(env.ocierr),
+			recordno, nil,
+			&errcode,
+			(*C.OraText)(unsafe.Pointer(&env.errBuf[0])),
+			C.ub4(len(env.errBuf)),
+			C.OCI_HTYPE_ERROR); r != C.OCI_SUCCESS {
+			break
+		}
+		records = append(records, ErrorRecord{
+			Code:    int(errcode),
+			Message: strings.TrimRight(C.GoString(&env.errBuf[0]), "\n"),
+		})
+	}
 	env.RUnlock()
+	if len(records) == 0 {
+		records = append(records, ErrorRecord{})
+	}
+	_stats.error(records[0].Code)
 	return er(&ORAError{
-		code:    int(errcode),
+		code:    records[0].Code,
 		prefix:  strings.Join(prefix, " "),
-		message: msg,
+		message: records[0].Message,
+		records: records,
 	})
 }
 
-type ORAError struct {
-	code            int
-	prefix, message string
-}
-
-func (e ORAError) Code() int {
-	return e.code
-}
-
-func (e *ORAError) Error() string {
-	if e == nil {
-		return ""
-	}
-	if e.message != "" {
-		if e.prefix != "" {
-			return e.prefix + ": " + e.message
-		}
-		return e.message
-	}
-	return fmt.Sprintf("ORA-%05d", e.code)
-}
-
 var b8Pool = sync.Pool{
 	New: func() interface{} {
 		p := unsafe.Pointer(C.malloc(8))
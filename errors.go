@@ -0,0 +1,365 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ORAError is the error returned by this package.
+//
+// It is either an error returned by the Oracle server or client (then Code
+// returns the ORA- code), or it wraps an other error with the caller info.
+//
+// ORAError implements Unwrap and Is, so
+//
+//	errors.Is(err, ora.ErrUniqueViolation)
+//
+// reports whether err is an ORA-00001 error, and
+//
+//	var oe *ora.ORAError
+//	errors.As(err, &oe)
+//
+// finds the ORAError in the chain of wrapped errors.
+type ORAError struct {
+	code            int
+	prefix, message string
+	records         []ErrorRecord
+
+	// the statement which returned the error
+	sql              string
+	parseOffset      int // 0-based
+	parseOffsetKnown bool
+	bindCount        int
+
+	err    error // the wrapped error
+	caller fmt.Stringer
+	trace  string
+}
+
+// ErrorRecord is a record of the OCI error stack.
+type ErrorRecord struct {
+	Code    int
+	Message string
+}
+
+// Code returns the ORA- code of the error, or of the first wrapped error
+// which has one, or the ORA-NNNNN in the text of the wrapped error;
+// 0 if there is none.
+func (e ORAError) Code() int {
+	if e.code != 0 || e.err == nil {
+		return e.code
+	}
+	if code := errCode(e.err); code != 0 {
+		return code
+	}
+	errS := e.err.Error()
+	i := strings.Index(errS, "ORA-")
+	if i < 0 {
+		return 0
+	}
+	var code int
+	fmt.Sscanf(errS[i+4:], "%d", &code)
+	return code
+}
+
+// Message returns the message of the Oracle error, without the caller info.
+func (e ORAError) Message() string {
+	if e.code != 0 || e.err == nil {
+		return e.message
+	}
+	if oe, ok := findORAError(e.err); ok {
+		return oe.Message()
+	}
+	return ""
+}
+
+// Records returns the whole error stack (such as the ORA-06512 lines of a
+// PL/SQL error), the first record being the error itself.
+func (e ORAError) Records() []ErrorRecord {
+	if e.code != 0 || e.err == nil {
+		return e.records
+	}
+	if oe, ok := findORAError(e.err); ok {
+		return oe.Records()
+	}
+	return nil
+}
+
+// SQL returns the (truncated) text of the statement which returned the error.
+func (e ORAError) SQL() string {
+	if oe, ok := e.stmtError(); ok {
+		return oe.sql
+	}
+	return ""
+}
+
+// ParseOffset returns the character offset of the parse error in the
+// statement, and whether it is known.
+func (e ORAError) ParseOffset() (int, bool) {
+	if oe, ok := e.stmtError(); ok && oe.parseOffsetKnown {
+		return oe.parseOffset, true
+	}
+	return 0, false
+}
+
+// BindCount returns the number of bind variables of the statement which
+// returned the error.
+func (e ORAError) BindCount() int {
+	if oe, ok := e.stmtError(); ok {
+		return oe.bindCount
+	}
+	return 0
+}
+
+// stmtError returns the ORAError in the chain which has the statement info.
+func (e ORAError) stmtError() (*ORAError, bool) {
+	if e.sql != "" {
+		return &e, true
+	}
+	for err := e.err; err != nil; err = unwrap(err) {
+		if oe, ok := err.(*ORAError); ok && oe.sql != "" {
+			return oe, true
+		}
+	}
+	return nil, false
+}
+
+func (e *ORAError) Error() string {
+	if e == nil {
+		return ""
+	}
+	var msg string
+	switch {
+	case e.err != nil:
+		msg = e.err.Error()
+	case e.message != "":
+		if e.prefix != "" {
+			msg = e.prefix + ": " + e.message
+		} else {
+			msg = e.message
+		}
+		for i := 1; i < len(e.records); i++ {
+			msg += "\n" + e.records[i].Message
+		}
+	default:
+		msg = fmt.Sprintf("ORA-%05d", e.code)
+	}
+	if e.sql != "" {
+		msg += e.stmtInfo()
+	}
+	if e.caller != nil {
+		if len(e.trace) > 0 {
+			return fmt.Sprintf("%v recovered: %v\n%s", e.caller, msg, e.trace)
+		}
+		return fmt.Sprintf("%v %v", e.caller, msg)
+	}
+	return msg
+}
+
+// Unwrap returns the wrapped error.
+func (e *ORAError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.err
+}
+
+// Is reports whether the ORA- code of e belongs to target, which is one
+// of the error categories (ErrUniqueViolation, ErrDeadlock ...).
+// The ORA-NNNNN in the text of a plain wrapped error is not considered.
+func (e *ORAError) Is(target error) bool {
+	c, ok := target.(*errCategory)
+	return ok && e != nil && c.has(errCode(e))
+}
+
+// Constraint returns the name of the violated constraint (as SCHEMA.NAME)
+// for the constraint violation errors (ORA-00001, ORA-02290, ORA-02291,
+// ORA-02292), and the name of the column (as "SCHEMA"."TABLE"."COLUMN")
+// for the NOT NULL violations (ORA-01400, ORA-01407).
+//
+// Returns "" for other errors.
+func (e ORAError) Constraint() string {
+	switch e.Code() {
+	case 1, 2290, 2291, 2292, 1400, 1407:
+	default:
+		return ""
+	}
+	msg := e.Message()
+	i := strings.IndexByte(msg, '(')
+	if i < 0 {
+		return ""
+	}
+	msg = msg[i+1:]
+	// the name may contain parentheses only in quotes
+	var inQuote bool
+	for j, r := range msg {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case ')':
+			if !inQuote {
+				return msg[:j]
+			}
+		}
+	}
+	return ""
+}
+
+// maxErrSQLLen is the maximum length of the statement text kept in an ORAError,
+// and maxErrMsgSQLLen is the maximum length of it in the error message.
+const (
+	maxErrSQLLen    = 4000
+	maxErrMsgSQLLen = 100
+)
+
+// setStmtInfo records the statement info in the error.
+// offset is the 0-based character offset of the parse error, if known.
+func (e *ORAError) setStmtInfo(sql string, offset int, known bool, bindCount int) {
+	if r := []rune(sql); len(r) > maxErrSQLLen {
+		sql = string(r[:maxErrSQLLen]) + "..."
+	}
+	if !known || offset < 0 {
+		offset, known = 0, false
+	}
+	e.sql, e.bindCount = sql, bindCount
+	e.parseOffset, e.parseOffsetKnown = offset, known
+}
+
+// stmtInfo returns the statement info to be appended to the error message:
+// the line of the parse error marked with a caret, and the bind count.
+func (e *ORAError) stmtInfo() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\nbinds: %d", e.bindCount)
+	if !e.parseOffsetKnown {
+		sql := e.sql
+		if r := []rune(sql); len(r) > maxErrMsgSQLLen {
+			sql = string(r[:maxErrMsgSQLLen]) + "..."
+		}
+		fmt.Fprintf(&buf, "\nsql: %s", sql)
+		return buf.String()
+	}
+	lines := strings.Split(e.sql, "\n")
+	offset := e.parseOffset
+	for i, line := range lines {
+		n := len([]rune(line))
+		if offset > n && i < len(lines)-1 {
+			offset -= n + 1
+			continue
+		}
+		fmt.Fprintf(&buf, "\nat line %d, column %d:\n%s\n%s^", i+1, offset+1,
+			line, strings.Repeat(" ", offset))
+		break
+	}
+	return buf.String()
+}
+
+// errCategory is a category of ORA- codes, to be used with errors.Is.
+type errCategory struct {
+	name  string
+	codes []int
+}
+
+func (c *errCategory) Error() string { return "ora: " + c.name }
+
+func (c *errCategory) has(code int) bool {
+	if code == 0 {
+		return false
+	}
+	for _, x := range c.codes {
+		if x == code {
+			return true
+		}
+	}
+	return false
+}
+
+// badConnCodes are the ORA- codes meaning that the connection is lost.
+// The same list decides ErrConnectionLost, driver.ErrBadConn and
+// the sessions dropped by the pools.
+var badConnCodes = []int{
+	28,    // your session has been killed
+	1012,  // not logged on
+	3113,  // end-of-file on communication channel
+	3114,  // not connected to ORACLE
+	3135,  // connection lost contact
+	12528, // TNS:listener: all appropriate instances are blocking new connections
+	12537, // TNS:connection closed
+	12545, // Connect failed because target host or object does not exist
+	12547, // TNS:lost contact
+}
+
+// Error categories, to be used with errors.Is.
+var (
+	// ErrUniqueViolation is ORA-00001: unique constraint violated.
+	ErrUniqueViolation error = &errCategory{"unique constraint violated", []int{1}}
+	// ErrNotNullViolation is ORA-01400 and ORA-01407: cannot insert/update to NULL.
+	ErrNotNullViolation error = &errCategory{"not null constraint violated", []int{1400, 1407}}
+	// ErrFKViolation is ORA-02291 and ORA-02292: integrity constraint violated.
+	ErrFKViolation error = &errCategory{"foreign key constraint violated", []int{2291, 2292}}
+	// ErrDeadlock is ORA-00060: deadlock detected.
+	ErrDeadlock error = &errCategory{"deadlock", []int{60}}
+	// ErrTimeout is a timeout: a call timeout (ORA-03156),
+	// a lock wait timeout (ORA-00051, ORA-02049, ORA-30006),
+	// a connect timeout (ORA-03136, ORA-12170), and the *CallTimeoutError.
+	ErrTimeout error = &errCategory{"timeout", []int{51, 2049, 3136, 3156, 12170, 30006}}
+	// ErrConnectionLost means that the connection to the server is lost:
+	// database/sql gets driver.ErrBadConn for it, the pools drop the session,
+	// and IsRetryable reports it as retryable.
+	ErrConnectionLost error = &errCategory{"connection lost", badConnCodes}
+	// ErrInvalidCredentials is ORA-01017: invalid username/password,
+	// and ORA-28000, ORA-28001: the account is locked, the password has expired.
+	ErrInvalidCredentials error = &errCategory{"invalid credentials", []int{1017, 28000, 28001}}
+	// ErrPackageStateDiscarded is ORA-04068, ORA-04061 and ORA-04065: the
+	// state of a package has been discarded, as it has been recompiled.
+	ErrPackageStateDiscarded error = &errCategory{"package state discarded", []int{4061, 4065, 4068}}
+	// ErrNoDataFound is ORA-01403: no data found.
+	ErrNoDataFound error = &errCategory{"no data found", []int{1403, 100}}
+)
+
+// unwrap returns the error wrapped by err, or nil.
+func unwrap(err error) error {
+	if u, ok := err.(interface {
+		Unwrap() error
+	}); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// findORAError returns the first *ORAError with an ORA- code in the chain of err.
+func findORAError(err error) (*ORAError, bool) {
+	for ; err != nil; err = unwrap(err) {
+		if oe, ok := err.(*ORAError); ok && oe.code != 0 {
+			return oe, true
+		}
+	}
+	return nil, false
+}
+
+// errCode returns the ORA- code in the chain of err, or 0.
+func errCode(err error) int {
+	if oe, ok := findORAError(err); ok {
+		return oe.code
+	}
+	return 0
+}
+
+// isErr is errors.Is for the error categories.
+func isErr(err error, category error) bool {
+	for ; err != nil; err = unwrap(err) {
+		if err == category {
+			return true
+		}
+		if x, ok := err.(interface {
+			Is(error) bool
+		}); ok && x.Is(category) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// +build go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestORAErrorIs tests the error categories and the constraint name parsing.
+func TestORAErrorIs(t *testing.T) {
+	wrap := func(err error) error { return &ORAError{caller: methodInfo{"Stmt", "exe"}, err: err} }
+	for i, tc := range []struct {
+		err        error
+		category   error
+		constraint string
+	}{
+		{err: wrap(&ORAError{code: 1, message: "ORA-00001: unique constraint (SCOTT.PK_EMP) violated"}),
+			category: ErrUniqueViolation, constraint: "SCOTT.PK_EMP"},
+		{err: wrap(wrap(&ORAError{code: 1400, message: `ORA-01400: cannot insert NULL into ("SCOTT"."EMP"."E(N)AME")`})),
+			category: ErrNotNullViolation, constraint: `"SCOTT"."EMP"."E(N)AME"`},
+		{err: &ORAError{code: 2291, message: "ORA-02291: integrity constraint (SCOTT.FK_DEPTNO) violated - parent key not found"},
+			category: ErrFKViolation, constraint: "SCOTT.FK_DEPTNO"},
+		{err: wrap(&ORAError{code: 60}), category: ErrDeadlock},
+		{err: wrap(&ORAError{code: 3113}), category: ErrConnectionLost},
+		{err: wrap(&CallTimeoutError{Err: &ORAError{code: 1013}}), category: ErrTimeout},
+		{err: wrap(&ORAError{code: 1403}), category: ErrNoDataFound},
+	} {
+		if !errors.Is(tc.err, tc.category) {
+			t.Errorf("%d. %v is not %v", i, tc.err, tc.category)
+		}
+		if isErr(tc.err, ErrInvalidCredentials) || errors.Is(tc.err, ErrPackageStateDiscarded) {
+			t.Errorf("%d. %v is in an other category", i, tc.err)
+		}
+		var oe *ORAError
+		if !errors.As(tc.err, &oe) {
+			t.Errorf("%d. %v is not an ORAError", i, tc.err)
+			continue
+		}
+		if got := oe.Constraint(); got != tc.constraint {
+			t.Errorf("%d. got constraint %q, wanted %q", i, got, tc.constraint)
+		}
+	}
+	if errors.Is(wrap(errors.New("ORA-00001")), ErrUniqueViolation) {
+		t.Errorf("plain error is categorized")
+	}
+}
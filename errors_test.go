@@ -0,0 +1,90 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestORAErrorCode tests the code of the wrapped and the plain errors.
+func TestORAErrorCode(t *testing.T) {
+	wrap := func(err error) error { return &ORAError{caller: methodInfo{"Stmt", "exe"}, err: err} }
+	for i, tc := range []struct {
+		err  error
+		code int
+	}{
+		{err: &ORAError{code: 1}, code: 1},
+		{err: wrap(wrap(&ORAError{code: 1400})), code: 1400},
+		{err: wrap(errors.New("ORA-12545: Connect failed")), code: 12545},
+		{err: wrap(errors.New("exec: ORA-00060: deadlock detected")), code: 60},
+		{err: wrap(errors.New("no code")), code: 0},
+	} {
+		if got := tc.err.(*ORAError).Code(); got != tc.code {
+			t.Errorf("%d. %v: got code %d, wanted %d", i, tc.err, got, tc.code)
+		}
+	}
+}
+
+// TestORAErrorStmtInfo tests the error stack and the parse error marking.
+func TestORAErrorStmtInfo(t *testing.T) {
+	oe := &ORAError{code: 6550, message: "ORA-06550: line 3, column 3:\nPLS-00201: identifier 'X' must be declared",
+		records: []ErrorRecord{
+			{Code: 6550, Message: "ORA-06550: line 3, column 3:\nPLS-00201: identifier 'X' must be declared"},
+			{Code: 6512, Message: "ORA-06512: at line 3"},
+		}}
+	oe.setStmtInfo("BEGIN\n  NULL;\n  x;\nEND;", 16, true, 2)
+	err := &ORAError{caller: methodInfo{"Stmt", "exe"}, err: oe}
+
+	if offset, ok := err.ParseOffset(); !ok || offset != 16 {
+		t.Errorf("got offset %d, %t; wanted 16", offset, ok)
+	}
+	if n := err.BindCount(); n != 2 {
+		t.Errorf("got bind count %d, wanted 2", n)
+	}
+	if n := len(err.Records()); n != 2 {
+		t.Errorf("got %d records, wanted 2", n)
+	}
+	want := `Stmt.exe ORA-06550: line 3, column 3:
+PLS-00201: identifier 'X' must be declared
+ORA-06512: at line 3
+binds: 2
+at line 3, column 3:
+  x;
+  ^`
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
+
+// TestORAErrorFirstCharacter tests the marking of a parse error at the first character.
+func TestORAErrorFirstCharacter(t *testing.T) {
+	oe := &ORAError{code: 900, message: "ORA-00900: invalid SQL statement"}
+	oe.setStmtInfo("SELCT 1 FROM DUAL", 0, true, 0)
+	err := &ORAError{caller: methodInfo{"Stmt", "qry"}, err: oe}
+	if offset, ok := err.ParseOffset(); !ok || offset != 0 {
+		t.Errorf("got offset %d, %t; wanted 0", offset, ok)
+	}
+	want := "Stmt.qry ORA-00900: invalid SQL statement\nbinds: 0\nat line 1, column 1:\nSELCT 1 FROM DUAL\n^"
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
+
+// TestORAErrorLongSQL tests that the message has only the start of a long statement.
+func TestORAErrorLongSQL(t *testing.T) {
+	sql := "SELECT " + strings.Repeat("col, ", 100) + "1 FROM DUAL"
+	oe := &ORAError{code: 904, message: "ORA-00904: invalid identifier"}
+	oe.setStmtInfo(sql, 0, false, 0)
+	err := &ORAError{caller: methodInfo{"Stmt", "qry"}, err: oe}
+	if got := err.SQL(); got != sql {
+		t.Errorf("got SQL of length %d, wanted %d", len(got), len(sql))
+	}
+	want := "Stmt.qry ORA-00904: invalid identifier\nbinds: 0\nsql: " + sql[:maxErrMsgSQLLen] + "..."
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
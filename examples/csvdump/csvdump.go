@@ -1,5 +1,6 @@
 /*
-   Package main in csvdump represents a cursor->csv dumper
+   Package main in csvdump represents a table or query -> CSV, TSV,
+   JSON Lines or SQL INSERT dumper.
 
    Copyright 2013 Tamás Gulácsi
 
@@ -18,154 +19,108 @@
 package main
 
 import (
-	"bufio"
-	"database/sql"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
-	"github.com/tgulacsi/go/orahlp"
-
-	"gopkg.in/errgo.v1"
-	"gopkg.in/rana/ora.v4/examples/connect"
+	"gopkg.in/rana/ora.v4"
+	"gopkg.in/rana/ora.v4/export"
 )
 
-func getQuery(table, where string, columns []string) string {
-	if strings.HasPrefix(table, "SELECT ") {
-		return table
-	}
-	cols := "*"
-	if len(columns) > 0 {
-		cols = strings.Join(columns, ", ")
+func main() {
+	flagConnect := flag.String("connect", os.Getenv("DSN"), "database connection string")
+	flagOut := flag.String("o", "-", "output file (- for stdout)")
+	flagFormat := flag.String("format", "", "output format: csv, tsv, jsonl or sql (default: by the output file extension)")
+	flagSep := flag.String("sep", ";", "csv field separator")
+	flagHeader := flag.Bool("header", true, "write the column names as the first record of csv and tsv")
+	flagInsertTable := flag.String("insert-table", "", "table of the sql INSERT statements (default: the dumped table)")
+	flagSCN := flag.Uint64("scn", 0, "read the rows as of this SCN (default: the current)")
+	flagWorkers := flag.Int("workers", runtime.GOMAXPROCS(0), "number of parallel sessions")
+	flagChunks := flag.Int("chunks", 0, "number of ROWID ranges (default: 4*workers, 1 for views)")
+	flagLOB := flag.String("lob", "inline", "binary and long values: inline (hex), base64 or sidecar")
+	flagLOBDir := flag.String("lob-dir", "", "directory of the sidecar LOB files")
+	flagCompress := flag.Bool("z", false, "gzip the output (default: by the .gz extension)")
+	flagProgress := flag.Bool("progress", false, "report progress on stderr")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <table or SELECT query> [where [columns...]]\n", os.Args[0])
+		flag.PrintDefaults()
 	}
-	if where == "" {
-		return "SELECT " + cols + " FROM " + table
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
 	}
-	return "SELECT " + cols + " FROM " + table + " WHERE " + where
-}
 
-func dump(w io.Writer, qry string) error {
-	db, err := connect.GetConnection("")
-	if err != nil {
-		return errgo.Notef(err, "connect to database")
+	cfg := export.Config{
+		Comma:       ([]rune(*flagSep))[0],
+		Header:      *flagHeader,
+		InsertTable: *flagInsertTable,
+		SCN:         *flagSCN,
+		Workers:     *flagWorkers,
+		Chunks:      *flagChunks,
+		LOBDir:      *flagLOBDir,
+		Compress:    *flagCompress,
 	}
-	defer db.Close()
-	columns, err := GetColumns(db, qry)
-	if err != nil {
-		return errgo.Notef(err, "get column converters", err)
+	if q := flag.Arg(0); strings.HasPrefix(strings.ToUpper(q), "SELECT ") {
+		cfg.Query = q
+	} else {
+		cfg.Table = q
+		if flag.NArg() > 1 {
+			cfg.Where = flag.Arg(1)
+			cfg.Columns = flag.Args()[2:]
+		}
 	}
-	log.Printf("columns: %#v", columns)
-	row := make([]interface{}, len(columns))
-	rowP := make([]interface{}, len(row))
-	for i := range row {
-		rowP[i] = &row[i]
+	var err error
+	ext := strings.TrimSuffix(*flagOut, ".gz")
+	if ext != *flagOut {
+		cfg.Compress = true
 	}
-
-	rows, err := db.Query(qry)
-	if err != nil {
-		return errgo.Newf("error executing %q: %s", qry, err)
+	if *flagFormat == "" {
+		// unknown extensions are written as csv
+		cfg.Format, _ = export.ParseFormat(filepath.Ext(ext))
+	} else if cfg.Format, err = export.ParseFormat(*flagFormat); err != nil {
+		log.Fatal(err)
 	}
-	defer rows.Close()
-
-	bw := bufio.NewWriterSize(w, 65536)
-	defer bw.Flush()
-	for i, col := range columns {
-		if i > 0 {
-			bw.Write([]byte{';'})
-		}
-		bw.Write([]byte{'"'})
-		bw.WriteString(col.Name)
-		bw.Write([]byte{'"'})
+	if cfg.LOB, err = export.ParseLOBMode(*flagLOB); err != nil {
+		log.Fatal(err)
 	}
-	bw.Write([]byte{'\n'})
-	n := 0
-	for rows.Next() {
-		if err = rows.Scan(rowP...); err != nil {
-			return errgo.Notef(err, "scan %d. row", n+1)
-		}
-		for i, data := range row {
-			if i > 0 {
-				bw.Write([]byte{';'})
-			}
-			if data == nil {
-				continue
-			}
-			bw.WriteString(columns[i].String(data))
+	if *flagProgress {
+		cfg.Progress = func(p export.Progress) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d chunks, %d rows in %s: %.3f rows/sec ",
+				p.Done, p.Chunks, p.Rows, p.Elapsed.Truncate(time.Second), float64(p.Rows)/p.Elapsed.Seconds())
 		}
-		bw.Write([]byte{'\n'})
-		n++
-	}
-	log.Printf("written %d rows.", n)
-	err = rows.Err()
-	if err != nil {
-		return err
 	}
-	return nil
-}
-
-type ColConverter func(interface{}) string
-
-type Column struct {
-	Name   string
-	String ColConverter
-}
 
-func GetColumns(db *sql.DB, qry string) (cols []Column, err error) {
-	desc, err := orahlp.DescribeSQLQuery(db, qry)
-	if err != nil {
-		return nil, errgo.Newf("error getting description for %q: %s", qry, err)
-	}
-	log.Printf("desc: %#v", desc)
-	var ok bool
-	cols = make([]Column, len(desc))
-	for i, col := range desc {
-		cols[i].Name = col.Name
-		if cols[i].String, ok = converters[col.Type]; !ok {
-			cols[i].String = defaultConverter
-			log.Printf("no converter for type %d (column name: %s)", col.Type, col.Name)
+	var w io.Writer = os.Stdout
+	if *flagOut != "-" {
+		fh, err := os.Create(*flagOut)
+		if err != nil {
+			log.Fatal(err)
 		}
+		defer fh.Close()
+		w = fh
 	}
-	return cols, nil
-}
-
-func defaultConverter(data interface{}) string { return fmt.Sprintf("%v", data) }
 
-var converters = map[int]ColConverter{
-	1: func(data interface{}) string { //VARCHAR2
-		return fmt.Sprintf("%q", data.(string))
-	},
-	6: func(data interface{}) string { //NUMBER
-		return fmt.Sprintf("%v", data)
-	},
-	96: func(data interface{}) string { //CHAR
-		return fmt.Sprintf("%q", data.(string))
-	},
-	156: func(data interface{}) string { //DATE
-		return `"` + data.(time.Time).Format(time.RFC3339) + `"`
-	},
-}
-
-func main() {
-	var (
-		where   string
-		columns []string
-	)
+	pool, err := ora.NewPool(*flagConnect, cfg.Workers)
+	if err != nil {
+		log.Fatalf("connect to %q: %v", *flagConnect, err)
+	}
+	defer pool.Close()
 
-	flag.Parse()
-	if flag.NArg() > 1 {
-		where = flag.Arg(1)
-		if flag.NArg() > 2 {
-			columns = flag.Args()[2:]
-		}
+	res, err := export.Export(context.Background(), pool, w, cfg)
+	if *flagProgress {
+		fmt.Fprintln(os.Stderr)
 	}
-	qry := getQuery(flag.Arg(0), where, columns)
-	if err := dump(os.Stdout, qry); err != nil {
-		log.Printf("error dumping: %s", err)
+	log.Printf("written %d rows in %d chunks as of SCN %d.", res.Rows, res.Chunks, res.SCN)
+	if err != nil {
+		log.Printf("error dumping: %v", err)
 		os.Exit(1)
 	}
-	os.Exit(0)
 }
@@ -14,200 +14,141 @@
    limitations under the License.
 */
 
-// Package main in csvload is a csv -> table loader.
+// Package main in csvload is a CSV, TSV or JSON Lines -> table loader.
+//
+// Usage:
+//
+//	csvload [flags] <file> <table>
 package main
 
 import (
-	"bufio"
-	"database/sql"
-	"encoding/csv"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/tgulacsi/go/term"
 	"github.com/tgulacsi/go/text"
-	"golang.org/x/text/encoding"
 	"gopkg.in/inconshreveable/log15.v2"
+	"gopkg.in/rana/ora.v4"
+	"gopkg.in/rana/ora.v4/load"
 )
 
 var Log = log15.New()
 
-const batchLen = 16
-
 func main() {
 	Log.SetHandler(log15.StderrHandler)
-	flagConnect := flag.String("connect", os.Getenv("BRUNO_ID"), "database connection string")
-	flagCharset := flag.String("charset", term.GetTTYEncodingName(), "input charset of the csv")
-	flagTruncate := flag.Bool("truncate", false, "truncate table?")
+	flagConnect := flag.String("connect", os.Getenv("DSN"), "database connection string")
+	flagCharset := flag.String("charset", term.GetTTYEncodingName(), "input charset")
+	flagFormat := flag.String("format", "", "input format: csv, tsv or jsonl (default: by the file extension)")
 	flagSep := flag.String("sep", ";", "csv field separator")
+	flagColumns := flag.String("columns", "", "comma separated target columns (default: the header of the csv)")
+	flagMode := flag.String("mode", "append", "load mode: append, truncate or merge")
+	flagKeys := flag.String("keys", "", "comma separated key columns for merge")
+	flagDateFormats := flag.String("date-formats", "", "| separated Go time layouts of the dates")
+	flagWorkers := flag.Int("workers", runtime.GOMAXPROCS(0), "number of parallel sessions")
+	flagBatch := flag.Int("batch", 1024, "rows per insert and commit")
+	flagBad := flag.String("bad", "", "file for the rejected records (default: <file>.bad)")
+	flagMaxErrors := flag.Int("max-errors", 0, "stop after so many rejected records")
 	flag.Parse()
-
-	var enc encoding.Encoding
-	if *flagCharset != "" {
-		enc = text.GetEncoding(*flagCharset)
-		if enc == nil {
-			Log.Error("unknown charset " + *flagCharset)
-			os.Exit(1)
-		}
+	if flag.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <file> <table>\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
 	}
-
-	db, err := sql.Open("ora", *flagConnect)
-	if err != nil {
-		Log.Crit("connect to db", "dsn", *flagConnect, "error", err)
+	fn, table := flag.Arg(0), flag.Arg(1)
+
+	cfg := load.Config{
+		Table:     table,
+		Comma:     ([]rune(*flagSep))[0],
+		Workers:   *flagWorkers,
+		BatchSize: *flagBatch,
+		MaxErrors: *flagMaxErrors,
+	}
+	var err error
+	if *flagFormat == "" {
+		// unknown extensions are read as csv
+		cfg.Format, _ = load.ParseFormat(filepath.Ext(fn))
+	} else if cfg.Format, err = load.ParseFormat(*flagFormat); err != nil {
+		Log.Error("format", "error", err)
 		os.Exit(1)
 	}
-	defer db.Close()
-
-	fh, err := os.Open(flag.Arg(0))
-	if err != nil {
-		Log.Crit("open csv", "file", flag.Arg(0), "error", err)
+	switch *flagMode {
+	case "append":
+		cfg.Mode = load.Append
+	case "truncate":
+		cfg.Mode = load.Truncate
+	case "merge":
+		cfg.Mode = load.Merge
+	default:
+		Log.Error("unknown mode " + *flagMode)
 		os.Exit(1)
 	}
-	defer fh.Close()
-	r := io.Reader(fh)
-	if enc != nil {
-		Log.Debug("NewReader", "encoding", enc)
-		r = text.NewReader(bufio.NewReaderSize(r, 1<<20), enc)
+	if *flagColumns != "" {
+		cfg.Columns = strings.Split(*flagColumns, ",")
 	}
-
-	if *flagTruncate {
-		if _, err = db.Exec("TRUNCATE TABLE " + flag.Arg(1)); err != nil {
-			Log.Error("TRUNCATE", "table", flag.Arg(1), "error", err)
+	if *flagKeys != "" {
+		cfg.Keys = strings.Split(*flagKeys, ",")
+	}
+	if *flagDateFormats != "" {
+		cfg.DateFormats = strings.Split(*flagDateFormats, "|")
+	}
+	if *flagCharset != "" {
+		if cfg.Encoding = text.GetEncoding(*flagCharset); cfg.Encoding == nil {
+			Log.Error("unknown charset " + *flagCharset)
 			os.Exit(1)
 		}
 	}
-	if os.Getenv("GOMAXPROCS") == "" {
-		Log.Info("Setting GOMAXPROCS", "numCPU", runtime.NumCPU())
-		runtime.GOMAXPROCS(runtime.NumCPU())
-	}
 
-	cr := csv.NewReader(bufio.NewReaderSize(r, 16<<20))
-	cr.Comma = ([]rune(*flagSep))[0]
-	cr.TrimLeadingSpace = true
-	cr.LazyQuotes = true
-	if err := load(db, flag.Arg(1), cr); err != nil {
-		Log.Error("load", "error", err)
-		os.Exit(2)
-	}
-}
-
-func load(db *sql.DB, tbl string, cr *csv.Reader) error {
-	head, err := cr.Read()
-	if err != nil {
-		return err
+	var r io.Reader = os.Stdin
+	if fn != "-" {
+		fh, err := os.Open(fn)
+		if err != nil {
+			Log.Crit("open input", "file", fn, "error", err)
+			os.Exit(1)
+		}
+		defer fh.Close()
+		r = fh
 	}
-	cr.FieldsPerRecord = len(head)
-	marks := make([]string, len(head))
-	for i := range marks {
-		marks[i] = "?"
+	badName := *flagBad
+	if badName == "" {
+		badName = fn + ".bad"
+		if fn == "-" {
+			badName = "csvload.bad"
+		}
 	}
-	qry := "INSERT INTO " + tbl + " (" + strings.Join(head, ",") + ") VALUES (" + strings.Join(marks, ",") + ")"
-	Log.Info("insert", "qry", qry)
-
-	var wg sync.WaitGroup
-	conc := runtime.GOMAXPROCS(-1)
-	blocks := make(chan [][]string, conc)
-	errs := make(chan error, conc)
-	rowCount := new(int32)
-
-	R := func(f func() error) {
-		defer wg.Done()
-		errs <- f()
+	bad, err := os.Create(badName)
+	if err != nil {
+		Log.Crit("create bad file", "file", badName, "error", err)
+		os.Exit(1)
 	}
-	for i := 0; i < conc; i++ {
-		wg.Add(1)
-		go R(func() error {
-			var (
-				tx *sql.Tx
-				st *sql.Stmt
-			)
-			n := 0
-			values := make([]interface{}, len(marks))
+	cfg.BadFile = bad
 
-			for block := range blocks {
-				for _, row := range block {
-					for i, v := range row {
-						values[i] = v
-					}
-					if tx == nil {
-						if st != nil {
-							st.Close()
-							st = nil
-						}
-						if tx, err = db.Begin(); err != nil {
-							return err
-						}
-						if st, err = tx.Prepare(qry); err != nil {
-							return err
-						}
-					}
-					if _, err = st.Exec(values...); err != nil {
-						return fmt.Errorf("error inserting %q with %q: %v", row, qry, err)
-					}
-					n++
-					atomic.AddInt32(rowCount, 1)
-					if n%1000 == 0 {
-						if err = tx.Commit(); err != nil {
-							return err
-						}
-						tx = nil
-						Log.Info("commit", "n", n, "rowCount", atomic.LoadInt32(rowCount))
-					}
-				}
-			}
-			Log.Info("commit", "n", n, "rowCount", atomic.LoadInt32(rowCount))
-			if st != nil {
-				st.Close()
-			}
-			if tx != nil {
-				return tx.Commit()
-			}
-			return nil
-		})
+	pool, err := ora.NewPool(*flagConnect, cfg.Workers)
+	if err != nil {
+		Log.Crit("connect to db", "dsn", *flagConnect, "error", err)
+		os.Exit(1)
 	}
+	defer pool.Close()
 
-	var block [][]string
-	t := time.Now()
-	for {
-		row, err := cr.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			Log.Error("read row", "error", err)
-			continue
-		}
-		if block == nil {
-			block = make([][]string, 0, batchLen)
-		}
-		block = append(block, row)
-		if len(block) == batchLen {
-			blocks <- block
-			block = nil
-		}
+	start := time.Now()
+	res, err := load.Load(context.Background(), pool, r, cfg)
+	d := time.Since(start)
+	if closeErr := bad.Close(); closeErr != nil && err == nil {
+		err = closeErr
 	}
-	if len(block) > 0 {
-		blocks <- block
+	if res.Rejected == 0 {
+		os.Remove(badName)
 	}
-	close(blocks)
-	wg.Wait()
-	n, d := atomic.LoadInt32(rowCount), time.Since(t)
-	fmt.Fprintf(os.Stderr, "Written %d rows under %s: %.3f rows/sec\n",
-		n, d, float64(n)/float64(d/time.Second))
-	close(errs)
-	for err := range errs {
-		if err == nil {
-			continue
-		}
-		return err
+	fmt.Fprintf(os.Stderr, "Written %d rows (rejected %d) under %s: %.3f rows/sec\n",
+		res.Rows, res.Rejected, d, float64(res.Rows)/d.Seconds())
+	if err != nil {
+		Log.Error("load", "error", err)
+		os.Exit(2)
 	}
-
-	return nil
 }
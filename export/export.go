@@ -0,0 +1,450 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+// Package export exports Oracle tables or queries as CSV, TSV, JSON Lines
+// or SQL INSERT statements.
+//
+// Tables are split into ROWID ranges, which are exported in parallel sessions,
+// all reading as of the same SCN, so the export is consistent.
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/rana/ora.v4"
+)
+
+// Config is the configuration of Export.
+type Config struct {
+	// Table is the exported table, as TABLE or SCHEMA.TABLE.
+	Table string
+	// Columns are the exported columns of Table; the default is all of them.
+	Columns []string
+	// Where is an optional condition for the rows of Table.
+	Where string
+	// Query is exported instead of Table, if given.
+	// Queries are not split, and read as of SCN only if it is given
+	// (with DBMS_FLASHBACK).
+	Query string
+	// Format of the output.
+	Format Format
+	// Comma is the field separator of CSV; the default is ','.
+	Comma rune
+	// Header writes the column names as the first record of CSV and TSV.
+	Header bool
+	// InsertTable is the table of the SQL INSERT statements;
+	// the default is Table, or "T" for a Query.
+	InsertTable string
+	// SCN is the system change number the rows are read as of;
+	// the default is the current SCN.
+	SCN uint64
+	// Workers is the number of parallel sessions; the default is GOMAXPROCS.
+	Workers int
+	// Chunks is the number of ROWID ranges Table is split into, by its
+	// extents; the default is 4*Workers. 1 disables the split.
+	Chunks int
+	// LOB is the way the binary and long values are written.
+	LOB LOBMode
+	// LOBThreshold is the length above which the values are written
+	// to sidecar files with LOBSidecar; the default is 4000.
+	LOBThreshold int
+	// LOBDir is the directory of the sidecar files.
+	LOBDir string
+	// Compress gzips the output.
+	Compress bool
+	// TempDir is the directory of the temporary files of the chunks;
+	// the default is os.TempDir().
+	TempDir string
+	// Progress is called after every 10000 rows and every finished chunk.
+	// The calls are serialized.
+	Progress func(Progress)
+}
+
+// Progress is the state of the export.
+type Progress struct {
+	// Chunks is the number of chunks, Done of the finished ones.
+	Chunks, Done int
+	// Rows is the number of the rows read.
+	Rows int64
+	// Elapsed is the time since the start of the export.
+	Elapsed time.Duration
+}
+
+// Result is the result of Export.
+type Result struct {
+	// Rows is the number of the exported rows.
+	Rows int64
+	// SCN is the system change number the rows were read as of;
+	// 0 for a Query without Config.SCN.
+	SCN uint64
+	// Chunks is the number of the exported chunks.
+	Chunks int
+}
+
+const progressRows = 10000
+
+// chunk is a ROWID range of the table.
+type chunk struct {
+	index  int
+	lo, hi string
+	file   *os.File // the output of the chunk, nil if written directly
+	rows   int64
+	err    error
+	done   chan struct{}
+}
+
+// Export writes the rows of the table or query to w, with the sessions of pool.
+//
+// The chunks of the table are written to temporary files by the workers,
+// and copied to w in order.
+func Export(ctx context.Context, pool *ora.Pool, w io.Writer, cfg Config) (Result, error) {
+	var res Result
+	if cfg.Table == "" && cfg.Query == "" {
+		return res, errors.New("no table or query")
+	}
+	if cfg.LOB == LOBSidecar && cfg.LOBDir == "" {
+		return res, errors.New("LOBSidecar needs LOBDir")
+	}
+	if cfg.Comma == 0 {
+		cfg.Comma = ','
+	}
+	if cfg.Format == TSV {
+		cfg.Comma = '\t'
+	}
+	if cfg.InsertTable == "" {
+		if cfg.InsertTable = cfg.Table; cfg.Query != "" {
+			cfg.InsertTable = "T"
+		}
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.Chunks <= 0 {
+		cfg.Chunks = 4 * cfg.Workers
+	}
+	if cfg.LOBThreshold <= 0 {
+		cfg.LOBThreshold = 4000
+	}
+
+	chunks := []*chunk{{}}
+	if cfg.Query == "" || cfg.SCN != 0 {
+		ses, err := pool.Get()
+		if err != nil {
+			return res, err
+		}
+		if cfg.SCN == 0 {
+			cfg.SCN, err = ses.CurrentSCN()
+		}
+		if err == nil && cfg.Query == "" && cfg.Chunks > 1 {
+			chunks, err = split(ctx, ses, cfg)
+		}
+		ses.Close()
+		if err != nil {
+			return res, err
+		}
+	}
+	res.SCN, res.Chunks = cfg.SCN, len(chunks)
+	for i, c := range chunks {
+		c.index, c.done = i, make(chan struct{})
+	}
+
+	var gz *gzip.Writer
+	if cfg.Compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	bw := bufio.NewWriterSize(w, 1<<16)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e := &exporter{cfg: cfg, cancel: cancel, start: time.Now(), progress: Progress{Chunks: len(chunks)}}
+	if len(chunks) == 1 {
+		e.direct = bw
+	}
+	todo := make(chan *chunk, len(chunks))
+	for _, c := range chunks {
+		todo <- c
+	}
+	close(todo)
+	workers := cfg.Workers
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.work(ctx, pool, todo)
+		}()
+	}
+
+	for _, c := range chunks {
+		<-c.done
+		if c.file == nil {
+			res.Rows += c.rows
+			continue
+		}
+		if e.getErr() == nil {
+			_, err := c.file.Seek(0, io.SeekStart)
+			if err == nil {
+				_, err = io.Copy(bw, c.file)
+			}
+			if err != nil {
+				e.setErr(err)
+			}
+			res.Rows += c.rows
+		}
+		c.file.Close()
+		os.Remove(c.file.Name())
+	}
+	wg.Wait()
+
+	err := e.getErr()
+	if err == nil && cfg.Format == SQL {
+		_, err = bw.WriteString("COMMIT;\n")
+	}
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	if gz != nil {
+		if cerr := gz.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return res, err
+}
+
+type exporter struct {
+	cfg    Config
+	cancel context.CancelFunc
+	direct io.Writer // the output of the only chunk
+	start  time.Time
+
+	mu       sync.Mutex
+	err      error
+	progress Progress
+}
+
+func (e *exporter) setErr(err error) {
+	e.mu.Lock()
+	if e.err == nil {
+		e.err = err
+		e.cancel()
+	}
+	e.mu.Unlock()
+}
+
+func (e *exporter) getErr() error {
+	e.mu.Lock()
+	err := e.err
+	e.mu.Unlock()
+	return err
+}
+
+// report adds the rows to the progress, and calls Config.Progress.
+func (e *exporter) report(rows int64, chunkDone bool) {
+	if e.cfg.Progress == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.progress.Rows += rows
+	if chunkDone {
+		e.progress.Done++
+	}
+	e.progress.Elapsed = time.Since(e.start)
+	e.cfg.Progress(e.progress)
+}
+
+// work exports the chunks on its own session. After an error, the remaining
+// chunks are only marked as done.
+func (e *exporter) work(ctx context.Context, pool *ora.Pool, todo <-chan *chunk) {
+	ses, err := pool.Get()
+	if err != nil {
+		e.setErr(err)
+	} else {
+		defer ses.Close()
+	}
+	for c := range todo {
+		if err = ctx.Err(); err == nil {
+			err = e.export(ctx, ses, c)
+		}
+		if err != nil {
+			c.err = err
+			e.setErr(err)
+		}
+		close(c.done)
+	}
+}
+
+// export exports the rows of the chunk.
+func (e *exporter) export(ctx context.Context, ses *ora.Ses, c *chunk) error {
+	qry, params := e.query(c)
+	stmt, err := ses.Prep(qry)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	cfg := stmt.Cfg().SetNumberFloat(ora.N)
+	if e.cfg.Query != "" && e.cfg.SCN != 0 {
+		cfg = cfg.SetSnapshot(ora.Snapshot{SCN: e.cfg.SCN})
+	}
+	stmt.SetCfg(cfg)
+	rset, err := stmt.QryContext(ctx, params...)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(rset.Columns))
+	for i, col := range rset.Columns {
+		names[i] = col.Name
+	}
+
+	w := e.direct
+	if w == nil {
+		if c.file, err = ioutil.TempFile(e.cfg.TempDir, "export-"); err != nil {
+			return err
+		}
+		w = c.file
+	}
+	f := newFormatter(w, &e.cfg, names, c.index)
+	if c.index == 0 && e.cfg.Header {
+		if err = f.writeHeader(); err != nil {
+			return err
+		}
+	}
+	var reported int64
+	for rset.NextContext(ctx) {
+		if err = f.write(rset.Row); err != nil {
+			return err
+		}
+		if c.rows++; c.rows-reported == progressRows {
+			e.report(progressRows, false)
+			reported = c.rows
+		}
+	}
+	if err = rset.Err(); err != nil {
+		return err
+	}
+	if err = f.flush(); err != nil {
+		return err
+	}
+	e.report(c.rows-reported, true)
+	return nil
+}
+
+// query returns the query of the chunk, and its parameters.
+func (e *exporter) query(c *chunk) (string, []interface{}) {
+	if e.cfg.Query != "" {
+		return e.cfg.Query, nil
+	}
+	cols := "*"
+	if len(e.cfg.Columns) != 0 {
+		cols = strings.Join(e.cfg.Columns, ", ")
+	}
+	qry := "SELECT " + cols + " FROM " + e.cfg.Table + " AS OF SCN :scn"
+	params := []interface{}{e.cfg.SCN}
+	var conds []string
+	if c.lo != "" {
+		conds = append(conds, "ROWID BETWEEN CHARTOROWID(:lo) AND CHARTOROWID(:hi)")
+		params = append(params, c.lo, c.hi)
+	}
+	if e.cfg.Where != "" {
+		conds = append(conds, "("+e.cfg.Where+")")
+	}
+	if len(conds) != 0 {
+		qry += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return qry, params
+}
+
+// splitQry groups the extents of the table (of all its partitions) into
+// at most :3 groups of about the same number of blocks, and returns
+// the ROWID range of each group.
+const splitQry = `WITH ext AS (
+  SELECT o.data_object_id obj, e.relative_fno fno, e.block_id blk, e.blocks
+    FROM dba_extents e, all_objects o
+    WHERE e.owner = NVL(:1, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')) AND e.segment_name = :2
+      AND e.segment_type IN ('TABLE', 'TABLE PARTITION', 'TABLE SUBPARTITION')
+      AND o.owner = e.owner AND o.object_name = e.segment_name
+      AND o.object_type = e.segment_type
+      AND NVL(o.subobject_name, '-') = NVL(e.partition_name, '-')
+), grp AS (
+  SELECT obj, fno, blk, blocks,
+         TRUNC((SUM(blocks) OVER (ORDER BY obj, fno, blk) - 1) * :3 / SUM(blocks) OVER ()) grp
+    FROM ext
+)
+SELECT ROWIDTOCHAR(DBMS_ROWID.ROWID_CREATE(1,
+         MIN(obj) KEEP (DENSE_RANK FIRST ORDER BY obj, fno, blk),
+         MIN(fno) KEEP (DENSE_RANK FIRST ORDER BY obj, fno, blk),
+         MIN(blk) KEEP (DENSE_RANK FIRST ORDER BY obj, fno, blk), 0)),
+       ROWIDTOCHAR(DBMS_ROWID.ROWID_CREATE(1,
+         MAX(obj) KEEP (DENSE_RANK LAST ORDER BY obj, fno, blk),
+         MAX(fno) KEEP (DENSE_RANK LAST ORDER BY obj, fno, blk),
+         MAX(blk + blocks - 1) KEEP (DENSE_RANK LAST ORDER BY obj, fno, blk), 32767))
+  FROM grp GROUP BY grp ORDER BY grp`
+
+// split splits the table into at most cfg.Chunks ROWID ranges of about
+// the same number of blocks, from its extents in DBA_EXTENTS, as
+// DBMS_PARALLEL_EXECUTE.CREATE_CHUNKS_BY_ROWID does.
+//
+// Views, tables without extents and tables of which DBA_EXTENTS is not
+// readable (ORA-00942) are exported in one chunk.
+func split(ctx context.Context, ses *ora.Ses, cfg Config) ([]*chunk, error) {
+	owner, name := ownerName(cfg.Table)
+	rset, err := ses.PrepAndQryContext(ctx, splitQry, owner, name, int64(cfg.Chunks))
+	if err != nil {
+		if oe, ok := err.(interface {
+			Code() int
+		}); ok && oe.Code() == 942 && ctx.Err() == nil {
+			return []*chunk{{}}, nil
+		}
+		return nil, err
+	}
+	var chunks []*chunk
+	for rset.NextContext(ctx) {
+		chunks = append(chunks, &chunk{lo: rset.Row[0].(string), hi: rset.Row[1].(string)})
+	}
+	if err = rset.Err(); err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 { // no extents
+		chunks = append(chunks, &chunk{})
+	}
+	return chunks, nil
+}
+
+// ownerName splits the TABLE or SCHEMA.TABLE name into the owner (empty
+// for the current schema) and the name, as they are in the data dictionary:
+// uppercased, unless quoted.
+func ownerName(table string) (owner, name string) {
+	ident := func(s string) string {
+		s = strings.TrimSpace(s)
+		if len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"' {
+			return s[1 : len(s)-1]
+		}
+		return strings.ToUpper(s)
+	}
+	// a dot in a quoted name is not a separator
+	var inQuote bool
+	for i, r := range table {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == '.' && !inQuote:
+			return ident(table[:i]), ident(table[i+1:])
+		}
+	}
+	return "", ident(table)
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package export
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/rana/ora.v4"
+)
+
+var (
+	testNames = []string{"ID", "NAME", "BORN", "DATA"}
+	testRows  = [][]interface{}{
+		{int64(1), "a,b", time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC), []byte{0, 255}},
+		{ora.Num(".5"), "it's", nil, nil},
+	}
+)
+
+func format(t *testing.T, cfg Config) string {
+	var buf bytes.Buffer
+	f := newFormatter(&buf, &cfg, testNames, 0)
+	if err := f.writeHeader(); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range testRows {
+		if err := f.write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		cfg   Config
+		await string
+	}{
+		{Config{Format: CSV, Comma: ','},
+			"ID,NAME,BORN,DATA\n1,\"a,b\",2017-03-04T05:06:07Z,00ff\n0.5,it's,,\n"},
+		{Config{Format: TSV, Comma: '\t', LOB: LOBBase64},
+			"ID\tNAME\tBORN\tDATA\n1\ta,b\t2017-03-04T05:06:07Z\tAP8=\n0.5\tit's\t\t\n"},
+		{Config{Format: JSONLines},
+			`{"ID":1,"NAME":"a,b","BORN":"2017-03-04T05:06:07Z","DATA":"00ff"}
+{"ID":0.5,"NAME":"it's","BORN":null,"DATA":null}
+`},
+		{Config{Format: SQL, InsertTable: "T"},
+			`INSERT INTO T ("ID", "NAME", "BORN", "DATA") VALUES (1, 'a,b', TO_TIMESTAMP_TZ('2017-03-04 05:06:07.000000000 +00:00', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM'), HEXTORAW('00ff'));
+INSERT INTO T ("ID", "NAME", "BORN", "DATA") VALUES (0.5, 'it''s', NULL, NULL);
+`},
+	} {
+		if got := format(t, tc.cfg); got != tc.await {
+			t.Errorf("%d: got\n%s\nwanted\n%s", tc.cfg.Format, got, tc.await)
+		}
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	got := format(t, Config{Format: CSV, Comma: ';', LOB: LOBSidecar, LOBThreshold: 2, LOBDir: dir})
+	await := "ID;NAME;BORN;DATA\n1;0000-000000001-001.txt;2017-03-04T05:06:07Z;00ff\n0.5;0000-000000002-001.txt;;\n"
+	if got != await {
+		t.Errorf("got\n%s\nwanted\n%s", got, await)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "0000-000000002-001.txt"))
+	if err != nil || string(b) != "it's" {
+		t.Errorf("got %q (%v), wanted %q", b, err, "it's")
+	}
+
+	got = format(t, Config{Format: SQL, InsertTable: "T", LOB: LOBSidecar, LOBThreshold: 3, LOBDir: dir})
+	await = `INSERT INTO T ("ID", "NAME", "BORN", "DATA") VALUES (1, 'a,b', TO_TIMESTAMP_TZ('2017-03-04 05:06:07.000000000 +00:00', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM'), HEXTORAW('00ff'));
+INSERT INTO T ("ID", "NAME", "BORN", "DATA") VALUES (0.5, 'it''s', NULL, NULL);
+`
+	if got != await {
+		t.Errorf("got\n%s\nwanted\n%s", got, await)
+	}
+}
+
+func TestOwnerName(t *testing.T) {
+	for _, tc := range []struct {
+		table, owner, name string
+	}{
+		{"emp", "", "EMP"},
+		{"scott.emp", "SCOTT", "EMP"},
+		{`scott."Emp.Old"`, "SCOTT", "Emp.Old"},
+		{`"Scott".emp`, "Scott", "EMP"},
+	} {
+		if owner, name := ownerName(tc.table); owner != tc.owner || name != tc.name {
+			t.Errorf("%s: got %q, %q, wanted %q, %q", tc.table, owner, name, tc.owner, tc.name)
+		}
+	}
+}
+
+func TestTextLiteral(t *testing.T) {
+	s := strings.Repeat("á", maxLiteral) + "'"
+	got := textLiteral(s)
+	await := "TO_CLOB('" + strings.Repeat("á", maxLiteral) + "')||TO_CLOB('''')"
+	if got != await {
+		t.Errorf("got %q, wanted %q", got, await)
+	}
+	if _, err := literal(make([]byte, maxRawLiteral+1)); err == nil {
+		t.Error("wanted error for a long binary value")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	e := &exporter{cfg: Config{Table: "scott.emp", Columns: []string{"empno", "ename"}, Where: "deptno = 10", SCN: 42}}
+	qry, params := e.query(&chunk{lo: "AAA", hi: "AAB"})
+	if await := "SELECT empno, ename FROM scott.emp AS OF SCN :scn WHERE ROWID BETWEEN CHARTOROWID(:lo) AND CHARTOROWID(:hi) AND (deptno = 10)"; qry != await {
+		t.Errorf("got %q, wanted %q", qry, await)
+	}
+	if await := []interface{}{uint64(42), "AAA", "AAB"}; !reflect.DeepEqual(params, await) {
+		t.Errorf("got %#v, wanted %#v", params, await)
+	}
+	e.cfg.Columns, e.cfg.Where = nil, ""
+	if qry, _ = e.query(&chunk{}); qry != "SELECT * FROM scott.emp AS OF SCN :scn" {
+		t.Errorf("got %q", qry)
+	}
+}
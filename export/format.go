@@ -0,0 +1,337 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/rana/ora.v4"
+)
+
+// Format is the format of the output.
+type Format uint8
+
+// Output formats.
+const (
+	CSV Format = iota
+	TSV
+	JSONLines
+	// SQL writes INSERT statements.
+	SQL
+)
+
+// ParseFormat returns the Format named csv, tsv, json, jsonl or sql.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return CSV, nil
+	case "tsv", "tab":
+		return TSV, nil
+	case "json", "jsonl", "ndjson":
+		return JSONLines, nil
+	case "sql":
+		return SQL, nil
+	}
+	return CSV, fmt.Errorf("unknown format %q", s)
+}
+
+// LOBMode is the way the binary and long values are written.
+// The SQL format always writes them inline, to keep the script runnable:
+// a binary value longer than 2000 bytes is an error there.
+type LOBMode uint8
+
+// LOB modes.
+const (
+	// LOBInline writes the binary values in hex, and the texts as they are.
+	LOBInline LOBMode = iota
+	// LOBBase64 writes the binary values in base64, and the texts as they are.
+	LOBBase64
+	// LOBSidecar writes the values longer than Config.LOBThreshold to
+	// separate files in Config.LOBDir, and their file names in their place.
+	// The shorter values are written as with LOBInline.
+	LOBSidecar
+)
+
+// ParseLOBMode returns the LOBMode named inline, base64 or sidecar.
+func ParseLOBMode(s string) (LOBMode, error) {
+	switch strings.ToLower(s) {
+	case "inline", "hex":
+		return LOBInline, nil
+	case "base64":
+		return LOBBase64, nil
+	case "sidecar", "file":
+		return LOBSidecar, nil
+	}
+	return LOBInline, fmt.Errorf("unknown LOB mode %q", s)
+}
+
+const (
+	// maxLiteral is the maximum number of characters of a SQL text literal
+	// written at once, safe for any database charset.
+	maxLiteral = 1000
+	// maxRawLiteral is the maximum length of a binary value written
+	// as a SQL literal.
+	maxRawLiteral = 2000
+
+	sqlTimeLayout = "2006-01-02 15:04:05.000000000 -07:00"
+)
+
+// number is the text of a number.
+type number string
+
+// formatter writes the rows of a chunk in the configured format.
+type formatter struct {
+	cfg    *Config
+	names  []string
+	chunk  int
+	row    int64
+	w      *bufio.Writer
+	cw     *csv.Writer
+	fields []string
+	insert string
+}
+
+func newFormatter(w io.Writer, cfg *Config, names []string, chunk int) *formatter {
+	f := &formatter{cfg: cfg, names: names, chunk: chunk, w: bufio.NewWriterSize(w, 1<<16)}
+	switch cfg.Format {
+	case CSV, TSV:
+		f.cw = csv.NewWriter(f.w)
+		f.cw.Comma = cfg.Comma
+		f.fields = make([]string, len(names))
+	case SQL:
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = `"` + name + `"`
+		}
+		f.insert = "INSERT INTO " + cfg.InsertTable + " (" + strings.Join(quoted, ", ") + ") VALUES ("
+	}
+	return f
+}
+
+// writeHeader writes the column names as a CSV or TSV record.
+func (f *formatter) writeHeader() error {
+	if f.cw == nil {
+		return nil
+	}
+	return f.cw.Write(f.names)
+}
+
+// write writes a row.
+func (f *formatter) write(values []interface{}) error {
+	f.row++
+	switch f.cfg.Format {
+	case JSONLines:
+		return f.writeJSON(values)
+	case SQL:
+		return f.writeSQL(values)
+	}
+	for i, v := range values {
+		s, err := f.text(i, normalize(v))
+		if err != nil {
+			return err
+		}
+		f.fields[i] = s
+	}
+	return f.cw.Write(f.fields)
+}
+
+func (f *formatter) writeJSON(values []interface{}) error {
+	f.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			f.w.WriteByte(',')
+		}
+		b, err := json.Marshal(f.names[i])
+		if err != nil {
+			return err
+		}
+		f.w.Write(b)
+		f.w.WriteByte(':')
+		switch x := normalize(v).(type) {
+		case nil:
+			f.w.WriteString("null")
+		case number:
+			f.w.WriteString(string(x))
+		default:
+			s, err := f.text(i, x)
+			if err != nil {
+				return err
+			}
+			if b, err = json.Marshal(s); err != nil {
+				return err
+			}
+			f.w.Write(b)
+		}
+	}
+	_, err := f.w.WriteString("}\n")
+	return err
+}
+
+func (f *formatter) writeSQL(values []interface{}) error {
+	f.w.WriteString(f.insert)
+	for i, v := range values {
+		if i > 0 {
+			f.w.WriteString(", ")
+		}
+		s, err := literal(normalize(v))
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.names[i], err)
+		}
+		f.w.WriteString(s)
+	}
+	_, err := f.w.WriteString(");\n")
+	return err
+}
+
+// flush writes out the buffered rows.
+func (f *formatter) flush() error {
+	if f.cw != nil {
+		f.cw.Flush()
+		if err := f.cw.Error(); err != nil {
+			return err
+		}
+	}
+	return f.w.Flush()
+}
+
+// text returns the text of the normalized value of the i-th column,
+// writing it to a sidecar file if it is long.
+func (f *formatter) text(i int, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case number:
+		return string(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case string:
+		if f.cfg.LOB == LOBSidecar && len(x) > f.cfg.LOBThreshold {
+			return f.sidecar(i, ".txt", []byte(x))
+		}
+		return x, nil
+	case []byte:
+		if f.cfg.LOB == LOBSidecar && len(x) > f.cfg.LOBThreshold {
+			return f.sidecar(i, ".bin", x)
+		}
+		if f.cfg.LOB == LOBBase64 {
+			return base64.StdEncoding.EncodeToString(x), nil
+		}
+		return hex.EncodeToString(x), nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// sidecar writes the data into a file in LOBDir, and returns its name.
+func (f *formatter) sidecar(i int, ext string, data []byte) (string, error) {
+	name := fmt.Sprintf("%04d-%09d-%03d%s", f.chunk, f.row, i, ext)
+	if err := ioutil.WriteFile(filepath.Join(f.cfg.LOBDir, name), data, 0644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// normalize returns the value as nil, number, string, []byte or time.Time.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, number, string, []byte, time.Time:
+		return v
+	case int64:
+		return number(strconv.FormatInt(x, 10))
+	case int, int32, int16, int8, uint64, uint32, uint16, uint8:
+		return number(fmt.Sprint(x))
+	case float64:
+		return floatNumber(x, 64)
+	case float32:
+		return floatNumber(float64(x), 32)
+	case bool:
+		if x {
+			return number("1")
+		}
+		return number("0")
+	case ora.OCINum:
+		return fixNumber(x.String())
+	case ora.Num:
+		return fixNumber(string(x))
+	case ora.Bfile:
+		if x.IsNull {
+			return nil
+		}
+		return x.DirectoryAlias + "/" + x.Filename
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func floatNumber(f float64, bitSize int) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+	return number(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// fixNumber adds the missing leading zero to numbers like .5
+func fixNumber(s string) number {
+	if strings.HasPrefix(s, ".") {
+		return number("0" + s)
+	}
+	if strings.HasPrefix(s, "-.") {
+		return number("-0" + s[1:])
+	}
+	return number(s)
+}
+
+// literal returns the SQL literal of the normalized value.
+func literal(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "NULL", nil
+	case number:
+		return string(x), nil
+	case time.Time:
+		return "TO_TIMESTAMP_TZ('" + x.Format(sqlTimeLayout) + "', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')", nil
+	case []byte:
+		if len(x) > maxRawLiteral {
+			return "", fmt.Errorf("binary value of %d bytes is too long for a SQL literal", len(x))
+		}
+		return "HEXTORAW('" + hex.EncodeToString(x) + "')", nil
+	case string:
+		return textLiteral(x), nil
+	}
+	return textLiteral(fmt.Sprint(v)), nil
+}
+
+// textLiteral quotes s, concatenating TO_CLOB parts if it is long.
+func textLiteral(s string) string {
+	quote := func(s string) string { return "'" + strings.Replace(s, "'", "''", -1) + "'" }
+	if len(s) <= maxLiteral {
+		return quote(s)
+	}
+	var parts []string
+	for len(s) > 0 {
+		i, n := 0, 0
+		for i < len(s) && n < maxLiteral {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			n++
+		}
+		parts = append(parts, "TO_CLOB("+quote(s[:i])+")")
+		s = s[i:]
+	}
+	return strings.Join(parts, "||")
+}
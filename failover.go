@@ -0,0 +1,153 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <stdint.h>
+#include <oci.h>
+
+extern sb4 goFailoverCallback(void *svchp, void *envhp, void *fo_ctx, ub4 fo_type, ub4 fo_event);
+extern void goEventCallback(void *evtctx, OCIEvent *eventhp);
+
+static sword setFailoverCallback(OCIServer *srvhp, OCIError *errhp, uintptr_t id) {
+	OCIFocbkStruct fo;
+	fo.callback_function = (OCICallbackFailover)goFailoverCallback;
+	fo.fo_ctx = (void *)id;
+	return OCIAttrSet(srvhp, OCI_HTYPE_SERVER, &fo, 0, OCI_ATTR_FOCBK, errhp);
+}
+
+static sword setEventCallback(OCIEnv *envhp, OCIError *errhp, uintptr_t id) {
+	sword r = OCIAttrSet(envhp, OCI_HTYPE_ENV, (void *)goEventCallback, 0, OCI_ATTR_EVTCBK, errhp);
+	if (r != OCI_SUCCESS) {
+		return r;
+	}
+	return OCIAttrSet(envhp, OCI_HTYPE_ENV, (void *)id, 0, OCI_ATTR_EVTCTX, errhp);
+}
+*/
+import "C"
+
+import (
+	"io"
+	"sync"
+)
+
+// FailoverType is the type of the Transparent Application Failover.
+type FailoverType uint32
+
+// Failover types.
+const (
+	FailoverNone    = FailoverType(C.OCI_FO_NONE)
+	FailoverSession = FailoverType(C.OCI_FO_SESSION)
+	FailoverSelect  = FailoverType(C.OCI_FO_SELECT)
+	FailoverTxnal   = FailoverType(C.OCI_FO_TXNAL)
+)
+
+// FailoverEvent is the phase of the Transparent Application Failover.
+type FailoverEvent uint32
+
+// Failover events.
+const (
+	// FailoverBegin means that the connection is lost, and failover begins.
+	FailoverBegin = FailoverEvent(C.OCI_FO_BEGIN)
+	// FailoverEnd means that the failover succeeded.
+	FailoverEnd = FailoverEvent(C.OCI_FO_END)
+	// FailoverAbort means that the failover is unsuccessful, and won't be retried.
+	FailoverAbort = FailoverEvent(C.OCI_FO_ABORT)
+	// FailoverReauth means that the user session is reauthenticated.
+	FailoverReauth = FailoverEvent(C.OCI_FO_REAUTH)
+	// FailoverError means that the failover was unsuccessful, but may be retried.
+	FailoverError = FailoverEvent(C.OCI_FO_ERROR)
+)
+
+// FailoverFunc is called at each phase of the Transparent Application Failover.
+//
+// For a FailoverError event, returning true makes OCI retry the failover;
+// sleep before returning, as the retry is immediate.
+// The return value is ignored for the other events.
+type FailoverFunc func(srv *Srv, typ FailoverType, event FailoverEvent) (retry bool)
+
+// registry maps ids to objects for the C callbacks, which can't hold Go pointers.
+type registry struct {
+	sync.RWMutex
+	m map[uint64]interface{}
+}
+
+func (r *registry) add(id uint64, v interface{}) {
+	r.Lock()
+	if r.m == nil {
+		r.m = make(map[uint64]interface{})
+	}
+	r.m[id] = v
+	r.Unlock()
+}
+func (r *registry) remove(id uint64) {
+	r.Lock()
+	delete(r.m, id)
+	r.Unlock()
+}
+func (r *registry) get(id uint64) interface{} {
+	r.RLock()
+	defer r.RUnlock()
+	return r.m[id]
+}
+func (r *registry) each(f func(interface{})) {
+	r.RLock()
+	vs := make([]interface{}, 0, len(r.m))
+	for _, v := range r.m {
+		vs = append(vs, v)
+	}
+	r.RUnlock()
+	for _, v := range vs {
+		f(v)
+	}
+}
+
+var failoverSrvs, eventEnvs, eventPools registry
+
+// setFailover registers the failover callback of the server.
+func (srv *Srv) setFailover(env *Env, ocisrv *C.OCIServer) error {
+	r := C.setFailoverCallback(ocisrv, env.ocierr, C.uintptr_t(srv.id))
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	failoverSrvs.add(srv.id, srv)
+	return nil
+}
+
+// setEvents registers the HA event (FAN) callback of the environment.
+func (env *Env) setEvents() error {
+	r := C.setEventCallback(env.ocienv, env.ocierr, C.uintptr_t(env.id))
+	if r == C.OCI_ERROR {
+		return env.ociError()
+	}
+	eventEnvs.add(env.id, env)
+	return nil
+}
+
+// markBad marks all the sessions of the server as failing over (or not),
+// for the pools to drop them instead of reusing.
+// Clearing keeps the sessions which got a lost connection error bad.
+func (srv *Srv) markBad(bad bool) {
+	srv.RLock()
+	openSess := srv.openSess
+	srv.RUnlock()
+	openSess.each(func(ses *Ses) { ses.setBad(badFailover, bad) })
+}
+
+// purgeBad closes the idle sessions marked bad in the pools of the environment.
+func (env *Env) purgeBad() {
+	eventPools.each(func(v interface{}) {
+		if p := v.(*Pool); p.env == env {
+			p.ses.Check(func(c io.Closer) bool {
+				pb := c.(sesSrvPB)
+				if !pb.Ses.isBad() {
+					return true
+				}
+				p.discard(pb)
+				return false
+			})
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+// The exported callbacks are in a separate file,
+// as the preamble of a file with //export may not contain definitions.
+
+/*
+#include <oci.h>
+*/
+import "C"
+
+import "unsafe"
+
+//export goFailoverCallback
+func goFailoverCallback(svchp, envhp, foCtx unsafe.Pointer, foType, foEvent C.ub4) C.sb4 {
+	srv, _ := failoverSrvs.get(uint64(uintptr(foCtx))).(*Srv)
+	if srv == nil {
+		return 0
+	}
+	f := srv.Cfg().OnFailover
+	if f == nil {
+		return 0
+	}
+	event := FailoverEvent(foEvent)
+	srv.logF(true, "failover type=%d event=%d", foType, foEvent)
+	switch event {
+	case FailoverBegin:
+		srv.markBad(true)
+	case FailoverEnd:
+		srv.markBad(false)
+	}
+	if f(srv, FailoverType(foType), event) && event == FailoverError {
+		return C.OCI_FO_RETRY
+	}
+	return 0
+}
+
+//export goEventCallback
+func goEventCallback(evtctx unsafe.Pointer, eventhp *C.OCIEvent) {
+	env, _ := eventEnvs.get(uint64(uintptr(evtctx))).(*Env)
+	if env == nil {
+		return
+	}
+	var status C.ub4
+	env.RLock()
+	r := C.OCIAttrGet(unsafe.Pointer(eventhp), C.OCI_HTYPE_EVENT,
+		unsafe.Pointer(&status), nil, C.OCI_ATTR_HA_STATUS, env.ocierr)
+	env.RUnlock()
+	if r == C.OCI_ERROR || status != C.OCI_HA_STATUS_DOWN {
+		return
+	}
+	// mark the affected servers down, for the pools to drop their sessions
+	attr := C.ub4(C.OCI_ATTR_HA_SRVFIRST)
+	for {
+		var ocisrv *C.OCIServer
+		env.RLock()
+		r = C.OCIAttrGet(unsafe.Pointer(eventhp), C.OCI_HTYPE_EVENT,
+			unsafe.Pointer(&ocisrv), nil, attr, env.ocierr)
+		env.RUnlock()
+		if r != C.OCI_SUCCESS || ocisrv == nil {
+			break
+		}
+		attr = C.OCI_ATTR_HA_SRVNEXT
+		env.RLock()
+		openSrvs := env.openSrvs
+		env.RUnlock()
+		openSrvs.each(func(srv *Srv) {
+			srv.RLock()
+			down := srv.ocisrv == ocisrv
+			srv.RUnlock()
+			if down {
+				env.logF(true, "HA event: %s is down", srv.sysName())
+				srv.markBad(true)
+			}
+		})
+	}
+	env.purgeBad()
+}
+
+//export goSubscrCallback
+func goSubscrCallback(ctx unsafe.Pointer, subscrhp *C.OCISubscription, payload unsafe.Pointer, payl C.ub4, descriptor unsafe.Pointer, mode C.ub4) C.ub4 {
+	sub, _ := subscrs.get(uint64(uintptr(ctx))).(*Subscription)
+	if sub != nil && descriptor != nil {
+		sub.notify(descriptor)
+	}
+	return C.ub4(subscrContinue)
+}
+
+// subscrContinue is OCI_CONTINUE, to be returned by the subscription callback
+// as an ub4: a negative constant cannot be converted to it.
+var subscrContinue = C.OCI_CONTINUE
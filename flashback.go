@@ -0,0 +1,242 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotKey = "snapshot"
+
+	// sysTimeExpr converts the TIMESTAMP WITH TIME ZONE expression to the
+	// time zone of the database server, as flashback expects it.
+	sysTimeExpr = "CAST(%s AT TIME ZONE TO_CHAR(SYSTIMESTAMP, 'TZR') AS TIMESTAMP)"
+)
+
+// Snapshot is a point in time the data can be read as of, by flashback:
+// a system change number, or if SCN is zero, a timestamp.
+//
+// Snapshot is a plain value, so it can be shared across sessions, to read
+// consistent data in all of them.
+type Snapshot struct {
+	SCN  uint64
+	Time time.Time
+}
+
+// IsZero reports whether the Snapshot is unset.
+func (s Snapshot) IsZero() bool { return s.SCN == 0 && s.Time.IsZero() }
+
+// String returns the flashback clause of the Snapshot, like "AS OF SCN 123".
+func (s Snapshot) String() string {
+	if s.SCN != 0 {
+		return "AS OF SCN " + strconv.FormatUint(s.SCN, 10)
+	}
+	if s.Time.IsZero() {
+		return ""
+	}
+	return "AS OF TIMESTAMP " + strings.Replace(sysTimeExpr, "%s", timeLiteral(s.Time), 1)
+}
+
+// Table returns the table name with the flashback clause, to rewrite
+// the SQL of queries, like "emp AS OF SCN 123".
+func (s Snapshot) Table(name string) string {
+	if s.IsZero() {
+		return name
+	}
+	return name + " " + s.String()
+}
+
+// Context returns a new context, with which the queries read the data
+// as of the Snapshot, as WithSnapshot.
+func (s Snapshot) Context(ctx context.Context) context.Context {
+	return WithSnapshot(ctx, s)
+}
+
+// WithSnapshot returns a new context, with which the queries
+// (Stmt.QryContext, and database/sql QueryContext) read the data
+// as of the Snapshot, overriding StmtCfg.Snapshot.
+func WithSnapshot(ctx context.Context, s Snapshot) context.Context {
+	return context.WithValue(ctx, snapshotKey, s)
+}
+
+// ctxSnapshot returns the Snapshot from the context, and
+// whether it exist at all.
+func ctxSnapshot(ctx context.Context) (Snapshot, bool) {
+	s, ok := ctx.Value(snapshotKey).(Snapshot)
+	return s, ok
+}
+
+// timeLiteral returns the TIMESTAMP WITH TIME ZONE literal of t.
+func timeLiteral(t time.Time) string {
+	return "TO_TIMESTAMP_TZ('" + t.Format("2006-01-02 15:04:05.000000000 -07:00") +
+		"', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')"
+}
+
+// CurrentSCN returns the current system change number of the database.
+//
+// It needs EXECUTE privilege on DBMS_FLASHBACK.
+func (ses *Ses) CurrentSCN() (uint64, error) {
+	return ses.qryUint("SELECT TO_CHAR(DBMS_FLASHBACK.GET_SYSTEM_CHANGE_NUMBER) FROM DUAL")
+}
+
+// TimeToSCN returns the system change number of the time (with TIMESTAMP_TO_SCN).
+func (ses *Ses) TimeToSCN(t time.Time) (uint64, error) {
+	return ses.qryUint("SELECT TO_CHAR(TIMESTAMP_TO_SCN("+strings.Replace(sysTimeExpr, "%s", ":1", 1)+")) FROM DUAL", t)
+}
+
+// Snapshot returns a Snapshot of the current system change number.
+func (ses *Ses) Snapshot() (Snapshot, error) {
+	scn, err := ses.CurrentSCN()
+	return Snapshot{SCN: scn}, err
+}
+
+// qryUint returns the first column of the first row of the query, as uint64.
+func (ses *Ses) qryUint(sql string, params ...interface{}) (uint64, error) {
+	rset, err := ses.PrepAndQry(sql, params...)
+	if err != nil {
+		return 0, err
+	}
+	defer rset.Exhaust()
+	var s string
+	if rset.Next() {
+		s, _ = rset.Row[0].(string)
+	}
+	if err = rset.Err(); err != nil {
+		return 0, err
+	}
+	if s == "" {
+		return 0, errF("no number returned by %q", sql)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// enableFlashback makes the session read the data as of the Snapshot
+// with DBMS_FLASHBACK, and returns the function to disable it.
+//
+// The cursors opened while flashback is enabled keep reading as of the
+// Snapshot after it is disabled.
+func (ses *Ses) enableFlashback(s Snapshot) (disable func() error, err error) {
+	if s.SCN != 0 {
+		_, err = ses.PrepAndExe("BEGIN DBMS_FLASHBACK.ENABLE_AT_SYSTEM_CHANGE_NUMBER(:1); END;", s.SCN)
+	} else {
+		_, err = ses.PrepAndExe("BEGIN DBMS_FLASHBACK.ENABLE_AT_TIME("+strings.Replace(sysTimeExpr, "%s", ":1", 1)+"); END;", s.Time)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := ses.PrepAndExe("BEGIN DBMS_FLASHBACK.DISABLE; END;")
+		return err
+	}, nil
+}
+
+// RowVersion is a version of a row, returned by Ses.FlashbackVersions.
+type RowVersion struct {
+	// StartSCN and StartTime are when the version was created;
+	// zero if it is older than the lower bound of the query.
+	StartSCN  uint64
+	StartTime time.Time
+	// EndSCN and EndTime are when the version was replaced or deleted;
+	// zero if it is still current (or was so at the upper bound).
+	EndSCN  uint64
+	EndTime time.Time
+	// XID is the identifier of the creating transaction, in hex.
+	XID string
+	// Operation is "I" for insert, "U" for update, "D" for delete,
+	// and "" if unknown.
+	Operation string
+	// Row is the row values, by column name.
+	Row map[string]interface{}
+}
+
+// FlashbackVersions returns the versions of the rows of the table with
+// the pk column values (or of all rows, if pk is empty), between from and to,
+// in the order of their creation (flashback version query).
+//
+// The zero from and to mean the oldest and the newest available version.
+// The bounds are compared as times if both are times, else as SCNs.
+func (ses *Ses) FlashbackVersions(table string, pk map[string]interface{}, from, to Snapshot) ([]RowVersion, error) {
+	var params []interface{}
+	asTime := from.SCN == 0 && to.SCN == 0 && !from.Time.IsZero() && !to.Time.IsZero()
+	bound := func(s Snapshot, dflt string) string {
+		if s.IsZero() {
+			return dflt
+		}
+		if s.SCN != 0 {
+			params = append(params, s.SCN)
+			return ":" + strconv.Itoa(len(params))
+		}
+		params = append(params, s.Time)
+		expr := strings.Replace(sysTimeExpr, "%s", ":"+strconv.Itoa(len(params)), 1)
+		if asTime {
+			return expr
+		}
+		return "TIMESTAMP_TO_SCN(" + expr + ")"
+	}
+	versions := "SCN "
+	if asTime {
+		versions = "TIMESTAMP "
+	}
+	versions += bound(from, "MINVALUE") + " AND " + bound(to, "MAXVALUE")
+
+	keys := make([]string, 0, len(pk))
+	for k := range pk {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conds := make([]string, len(keys))
+	for i, k := range keys {
+		params = append(params, pk[k])
+		conds[i] = k + " = :" + strconv.Itoa(len(params))
+	}
+	qry := `SELECT TO_CHAR(versions_startscn), versions_starttime,
+       TO_CHAR(versions_endscn), versions_endtime,
+       RAWTOHEX(versions_xid), versions_operation, T.*
+  FROM ` + table + ` VERSIONS BETWEEN ` + versions + ` T`
+	if len(conds) != 0 {
+		qry += "\n  WHERE " + strings.Join(conds, " AND ")
+	}
+	qry += "\n  ORDER BY versions_startscn NULLS FIRST, versions_endscn NULLS LAST"
+
+	rset, err := ses.PrepAndQry(qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rset.Exhaust()
+	var rows []RowVersion
+	for rset.Next() {
+		var v RowVersion
+		s, _ := rset.Row[0].(string)
+		if v.StartSCN, err = parseSCN(s); err != nil {
+			return rows, err
+		}
+		v.StartTime, _ = rset.Row[1].(time.Time)
+		s, _ = rset.Row[2].(string)
+		if v.EndSCN, err = parseSCN(s); err != nil {
+			return rows, err
+		}
+		v.EndTime, _ = rset.Row[3].(time.Time)
+		v.XID, _ = rset.Row[4].(string)
+		v.Operation, _ = rset.Row[5].(string)
+		v.Row = make(map[string]interface{}, len(rset.Columns)-6)
+		for i, col := range rset.Columns[6:] {
+			v.Row[col.Name] = rset.Row[6+i]
+		}
+		rows = append(rows, v)
+	}
+	return rows, rset.Err()
+}
+
+func parseSCN(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
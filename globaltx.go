@@ -0,0 +1,384 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+/*
+#include <string.h>
+#include <oci.h>
+
+static sword setXID(OCITrans *txnhp, OCIError *errhp, long formatID,
+		const void *gtrid, long gtridLen, const void *bqual, long bqualLen) {
+	XID xid;
+	memset(&xid, 0, sizeof(XID));
+	xid.formatID = formatID;
+	xid.gtrid_length = gtridLen;
+	xid.bqual_length = bqualLen;
+	if (gtridLen > 0) {
+		memcpy(xid.data, gtrid, gtridLen);
+	}
+	if (bqualLen > 0) {
+		memcpy(xid.data + gtridLen, bqual, bqualLen);
+	}
+	return OCIAttrSet(txnhp, OCI_HTYPE_TRANS, &xid, sizeof(XID), OCI_ATTR_XID, errhp);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// XID identifies a branch of a global (distributed) transaction,
+// as defined by the X/Open XA standard.
+type XID struct {
+	FormatID            int32
+	GlobalTransactionID []byte // at most 64 bytes
+	BranchQualifier     []byte // at most 64 bytes
+}
+
+// String returns the XID in the usual formatID.gtrid.bqual hex form.
+func (xid XID) String() string {
+	return fmt.Sprintf("%d.%X.%X", xid.FormatID, xid.GlobalTransactionID, xid.BranchQualifier)
+}
+
+func (xid XID) check() error {
+	if len(xid.GlobalTransactionID) == 0 || len(xid.GlobalTransactionID) > 64 {
+		return errF("XID global transaction ID length must be between 1 and 64, got %d", len(xid.GlobalTransactionID))
+	}
+	if len(xid.BranchQualifier) > 64 {
+		return errF("XID branch qualifier length must be at most 64, got %d", len(xid.BranchQualifier))
+	}
+	return nil
+}
+
+// Global transaction flags, to be used with TxFlags for StartGlobalTx.
+const (
+	// TxTight makes the branches of the global transaction tightly coupled:
+	// they share the locks. This is the default.
+	TxTight = uint32(C.OCI_TRANS_TIGHT)
+	// TxLoose makes the branches of the global transaction loosely coupled.
+	TxLoose = uint32(C.OCI_TRANS_LOOSE)
+)
+
+// GlobalTx is a branch of a global transaction, which may take part in a
+// two-phase commit coordinated by an external transaction manager.
+//
+// While the GlobalTx is attached, the statements of its Ses are not
+// auto-committed.
+type GlobalTx struct {
+	sync.RWMutex
+
+	XID XID
+
+	ses      *Ses
+	tx       *Tx            // registers the branch in Ses.openTxs while attached
+	ocitrans unsafe.Pointer // registered in Ses.openGlobalTxs while attached
+	prepared bool
+}
+
+// StartGlobalTx starts a new branch of the global transaction identified by xid.
+//
+// The TxFlags option accepts TxTight, TxLoose, TxReadOnly, TxReadWrite and
+// TxSerializable, and TxTimeout sets the number of seconds the branch can
+// be detached before it is rolled back by the server.
+func (ses *Ses) StartGlobalTx(xid XID, opts ...TxOption) (*GlobalTx, error) {
+	return ses.startGlobalTx(xid, C.OCI_TRANS_NEW, opts)
+}
+
+// JoinGlobalTx joins an existing branch of the global transaction identified
+// by xid, which is attached to an other session.
+func (ses *Ses) JoinGlobalTx(xid XID, opts ...TxOption) (*GlobalTx, error) {
+	return ses.startGlobalTx(xid, C.OCI_TRANS_JOIN, opts)
+}
+
+// ResumeGlobalTx attaches the branch identified by xid, detached previously
+// by GlobalTx.Detach, to this session.
+func (ses *Ses) ResumeGlobalTx(xid XID, opts ...TxOption) (*GlobalTx, error) {
+	return ses.startGlobalTx(xid, C.OCI_TRANS_RESUME, opts)
+}
+
+// startGlobalTx sets the XID on a new transaction handle, and calls OCITransStart
+// with the mode.
+func (ses *Ses) startGlobalTx(xid XID, mode C.ub4, opts []TxOption) (*GlobalTx, error) {
+	ses.log(_drv.Cfg().Log.Ses.StartTx, xid)
+	if err := ses.checkClosed(); err != nil {
+		return nil, errE(err)
+	}
+	if err := xid.check(); err != nil {
+		return nil, err
+	}
+	var o txOption
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var timeout = C.uword(60)
+	if o.timeout > 0 {
+		timeout = C.uword(o.timeout / time.Second)
+	}
+	gtx := &GlobalTx{XID: xid, ses: ses}
+	if err := gtx.attach(); err != nil {
+		return nil, err
+	}
+	ses.RLock()
+	env := ses.Env()
+	r := C.OCITransStart(
+		ses.ocisvcctx,       //OCISvcCtx    *svchp,
+		env.ocierr,          //OCIError     *errhp,
+		timeout,             //uword        timeout,
+		mode|C.ub4(o.flags)) //ub4          flags );
+	ses.RUnlock()
+	_stats.roundTrip()
+	if r == C.OCI_ERROR {
+		err := ses.markBad(env.ociError())
+		gtx.release()
+		return nil, errE(err)
+	}
+	return gtx, nil
+}
+
+// PendingGlobalTxs returns the XIDs of the prepared (in-doubt) branches
+// on the server, which wait for CommitPrepared or RollbackPrepared.
+//
+// This needs "GRANT SELECT ON dba_pending_transactions".
+func (ses *Ses) PendingGlobalTxs() ([]XID, error) {
+	stmt, err := ses.Prep("SELECT formatid, globalid, branchid FROM dba_pending_transactions",
+		I64, Bin, Bin)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rset, err := stmt.Qry()
+	if err != nil {
+		return nil, err
+	}
+	var xids []XID
+	for rset.Next() {
+		xids = append(xids, XID{
+			FormatID:            int32(rset.Row[0].(int64)),
+			GlobalTransactionID: rset.Row[1].([]byte),
+			BranchQualifier:     rset.Row[2].([]byte),
+		})
+	}
+	return xids, rset.Err()
+}
+
+// CommitPrepared commits the prepared (in-doubt) branch identified by xid,
+// for recovery after the failure of the session which prepared it.
+func (ses *Ses) CommitPrepared(xid XID) error {
+	if err := xid.check(); err != nil {
+		return err
+	}
+	gtx := &GlobalTx{XID: xid, ses: ses, prepared: true}
+	if err := gtx.attach(); err != nil {
+		return err
+	}
+	return gtx.Commit()
+}
+
+// RollbackPrepared rolls back the prepared (in-doubt) branch identified by xid,
+// for recovery after the failure of the session which prepared it.
+func (ses *Ses) RollbackPrepared(xid XID) error {
+	if err := xid.check(); err != nil {
+		return err
+	}
+	gtx := &GlobalTx{XID: xid, ses: ses, prepared: true}
+	if err := gtx.attach(); err != nil {
+		return err
+	}
+	return gtx.Rollback()
+}
+
+// attach allocates the transaction handle with the XID, and sets it on the
+// service context of the session.
+func (gtx *GlobalTx) attach() error {
+	ses := gtx.ses
+	env := ses.Env()
+	ocitrans, err := env.allocOciHandle(C.OCI_HTYPE_TRANS)
+	if err != nil {
+		return errE(err)
+	}
+	xid := gtx.XID
+	var gtrid, bqual unsafe.Pointer
+	if len(xid.GlobalTransactionID) > 0 {
+		gtrid = unsafe.Pointer(&xid.GlobalTransactionID[0])
+	}
+	if len(xid.BranchQualifier) > 0 {
+		bqual = unsafe.Pointer(&xid.BranchQualifier[0])
+	}
+	if r := C.setXID((*C.OCITrans)(ocitrans), env.ocierr, C.long(xid.FormatID),
+		gtrid, C.long(len(xid.GlobalTransactionID)),
+		bqual, C.long(len(xid.BranchQualifier)),
+	); r == C.OCI_ERROR {
+		err = errE(env.ociError())
+		env.freeOciHandle(ocitrans, C.OCI_HTYPE_TRANS)
+		return err
+	}
+	ses.RLock()
+	err = env.setAttr(unsafe.Pointer(ses.ocisvcctx), C.OCI_HTYPE_SVCCTX, ocitrans, 0, C.OCI_ATTR_TRANS)
+	ses.RUnlock()
+	if err != nil {
+		env.freeOciHandle(ocitrans, C.OCI_HTYPE_TRANS)
+		return err
+	}
+
+	tx := _drv.txPool.Get().(*Tx)
+	tx.cmu.Lock()
+	tx.Lock()
+	tx.ses = ses
+	if tx.id == 0 {
+		tx.id = _drv.txId.nextId()
+	}
+	tx.Unlock()
+	tx.cmu.Unlock()
+	ses.openTxs.add(tx)
+
+	gtx.Lock()
+	gtx.ocitrans, gtx.tx = ocitrans, tx
+	gtx.Unlock()
+	ses.openGlobalTxs.add(gtx)
+	return nil
+}
+
+// release detaches the transaction handle from the service context, and frees it.
+func (gtx *GlobalTx) release() {
+	gtx.Lock()
+	ses, ocitrans, tx := gtx.ses, gtx.ocitrans, gtx.tx
+	gtx.ocitrans, gtx.tx = nil, nil
+	gtx.Unlock()
+	if ses != nil && ocitrans != nil {
+		ses.openGlobalTxs.remove(gtx)
+	}
+	if tx != nil {
+		tx.closeWithRemove()
+	}
+	if ocitrans == nil || ses == nil {
+		return
+	}
+	env := ses.Env()
+	ses.RLock()
+	if ses.ocisvcctx != nil {
+		env.setAttr(unsafe.Pointer(ses.ocisvcctx), C.OCI_HTYPE_SVCCTX, nil, 0, C.OCI_ATTR_TRANS)
+	}
+	ses.RUnlock()
+	env.freeOciHandle(ocitrans, C.OCI_HTYPE_TRANS)
+}
+
+// checkIsOpen validates that the branch is attached to an open session.
+func (gtx *GlobalTx) checkIsOpen() error {
+	if gtx == nil {
+		return er("GlobalTx is closed.")
+	}
+	gtx.RLock()
+	ses, ocitrans := gtx.ses, gtx.ocitrans
+	gtx.RUnlock()
+	if ses == nil || ocitrans == nil {
+		return er("GlobalTx is closed.")
+	}
+	return ses.checkClosed()
+}
+
+// Prepare prepares the branch for a two-phase commit.
+//
+// Returns readOnly=true if the branch has not modified any data: such
+// a branch is already finished, and must not be committed.
+func (gtx *GlobalTx) Prepare() (readOnly bool, err error) {
+	if err = gtx.checkIsOpen(); err != nil {
+		return false, err
+	}
+	ses := gtx.ses
+	env := ses.Env()
+	ses.RLock()
+	r := C.OCITransPrepare(
+		ses.ocisvcctx, //OCISvcCtx    *svchp,
+		env.ocierr,    //OCIError     *errhp,
+		C.OCI_DEFAULT) //ub4          flags );
+	ses.RUnlock()
+	_stats.roundTrip()
+	switch r {
+	case C.OCI_ERROR:
+		return false, errE(ses.markBad(env.ociError()))
+	case C.OCI_SUCCESS_WITH_INFO:
+		// ORA-24767: transaction branch prepare returns read-only
+		gtx.release()
+		return true, nil
+	}
+	gtx.Lock()
+	gtx.prepared = true
+	gtx.Unlock()
+	return false, nil
+}
+
+// Commit commits the branch: with a two-phase commit if it has been
+// prepared, with a one-phase commit otherwise.
+func (gtx *GlobalTx) Commit() error {
+	if err := gtx.checkIsOpen(); err != nil {
+		return err
+	}
+	defer gtx.release()
+	gtx.RLock()
+	ses, mode := gtx.ses, C.ub4(C.OCI_DEFAULT)
+	if gtx.prepared {
+		mode = C.OCI_TRANS_TWOPHASE
+	}
+	gtx.RUnlock()
+	env := ses.Env()
+	ses.RLock()
+	r := C.OCITransCommit(
+		ses.ocisvcctx, //OCISvcCtx    *svchp,
+		env.ocierr,    //OCIError     *errhp,
+		mode)          //ub4          flags );
+	ses.RUnlock()
+	_stats.roundTrip()
+	if r == C.OCI_ERROR {
+		return errE(ses.markBad(env.ociError()))
+	}
+	return nil
+}
+
+// Rollback rolls back the branch.
+func (gtx *GlobalTx) Rollback() error {
+	if err := gtx.checkIsOpen(); err != nil {
+		return err
+	}
+	defer gtx.release()
+	ses := gtx.ses
+	env := ses.Env()
+	ses.RLock()
+	r := C.OCITransRollback(
+		ses.ocisvcctx, //OCISvcCtx    *svchp,
+		env.ocierr,    //OCIError     *errhp,
+		C.OCI_DEFAULT) //ub4          flags );
+	ses.RUnlock()
+	_stats.roundTrip()
+	if r == C.OCI_ERROR {
+		return errE(ses.markBad(env.ociError()))
+	}
+	return nil
+}
+
+// Detach detaches the branch from the session, so it can be resumed later,
+// on any session, with ResumeGlobalTx.
+func (gtx *GlobalTx) Detach() error {
+	if err := gtx.checkIsOpen(); err != nil {
+		return err
+	}
+	defer gtx.release()
+	ses := gtx.ses
+	env := ses.Env()
+	ses.RLock()
+	r := C.OCITransDetach(
+		ses.ocisvcctx, //OCISvcCtx    *svchp,
+		env.ocierr,    //OCIError     *errhp,
+		C.OCI_DEFAULT) //ub4          flags );
+	ses.RUnlock()
+	_stats.roundTrip()
+	if r == C.OCI_ERROR {
+		return errE(ses.markBad(env.ociError()))
+	}
+	return nil
+}
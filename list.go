@@ -101,6 +101,15 @@ func (l *srvList) closeAll(errs *list.List) {
 	l.items = l.items[:0] // clear all Srvs from srvList
 }
 
+func (l *srvList) each(f func(*Srv)) {
+	l.mu.Lock()
+	items := append([]*Srv(nil), l.items...)
+	l.mu.Unlock()
+	for _, item := range items {
+		f(item)
+	}
+}
+
 func (l *srvList) clear() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -209,6 +218,15 @@ func (l *sesList) closeAll(errs *list.List) {
 	l.items = l.items[:0] // clear all Sess from sesList
 }
 
+func (l *sesList) each(f func(*Ses)) {
+	l.mu.Lock()
+	items := append([]*Ses(nil), l.items...)
+	l.mu.Unlock()
+	for _, item := range items {
+		f(item)
+	}
+}
+
 func (l *sesList) clear() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -275,6 +293,114 @@ func (l *txList) len() int {
 	return len(l.items)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// subscrList
+////////////////////////////////////////////////////////////////////////////////
+type subscrList struct {
+	items []*Subscription
+	mu    sync.Mutex
+}
+
+func newSubscrList() *subscrList {
+	return &subscrList{items: make([]*Subscription, 0, 2)}
+}
+
+func (l *subscrList) add(s *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = append(l.items, s) // append item
+}
+
+func (l *subscrList) remove(s *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for n, item := range l.items {
+		if item == s {
+			l.items[n] = l.items[0]
+			l.items = l.items[1:]
+			break
+		}
+	}
+}
+
+func (l *subscrList) closeAll(errs *list.List) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, item := range l.items {
+		err := item.close() // close will not remove Subscription from openSubscrs
+		if err != nil {
+			errs.PushBack(errE(err))
+		}
+	}
+	l.items = l.items[:0] // clear all Subscriptions from subscrList
+}
+
+func (l *subscrList) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = l.items[:0]
+}
+
+func (l *subscrList) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// globalTxList
+////////////////////////////////////////////////////////////////////////////////
+type globalTxList struct {
+	items []*GlobalTx
+	mu    sync.Mutex
+}
+
+func newGlobalTxList() *globalTxList {
+	return &globalTxList{items: make([]*GlobalTx, 0, 1)}
+}
+
+func (l *globalTxList) add(g *GlobalTx) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = append(l.items, g) // append item
+}
+
+func (l *globalTxList) remove(g *GlobalTx) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for n, item := range l.items {
+		if item == g {
+			l.items[n] = l.items[0]
+			l.items = l.items[1:]
+			break
+		}
+	}
+}
+
+// releaseAll frees the transaction handles of the attached GlobalTxs,
+// without committing or rolling back their branches.
+func (l *globalTxList) releaseAll() {
+	l.mu.Lock()
+	items := l.items
+	l.items = nil
+	l.mu.Unlock()
+	for _, item := range items {
+		item.release() // release will not find the GlobalTx in the emptied list
+	}
+}
+
+func (l *globalTxList) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = l.items[:0]
+}
+
+func (l *globalTxList) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // stmtList
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,143 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package load
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/rana/ora.v4"
+)
+
+// DefaultDateFormats are the layouts the dates are parsed with by default.
+var DefaultDateFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Column is a column of the target table.
+type Column struct {
+	Name     string
+	DataType string
+	Scale    int // -1 if unknown
+}
+
+// converter converts the text of a field to the value to be bound.
+type converter func(string) (interface{}, error)
+
+// Describe returns the columns of the table (as TABLE or SCHEMA.TABLE),
+// in the order of their definition.
+func Describe(ses *ora.Ses, table string) ([]Column, error) {
+	schema, tbl := splitName(table)
+	rset, err := ses.PrepAndQry(`SELECT column_name, data_type, NVL(data_scale, -1)
+  FROM all_tab_columns
+  WHERE owner = NVL(:1, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')) AND table_name = :2
+  ORDER BY column_id`, schema, tbl)
+	if err != nil {
+		return nil, err
+	}
+	var cols []Column
+	for rset.Next() {
+		scale, _ := strconv.Atoi(fmt.Sprint(rset.Row[2]))
+		cols = append(cols, Column{
+			Name:     rset.Row[0].(string),
+			DataType: rset.Row[1].(string),
+			Scale:    scale,
+		})
+	}
+	if err = rset.Err(); err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %q not found", table)
+	}
+	return cols, nil
+}
+
+// splitName splits SCHEMA.NAME, unquoting or uppercasing the parts.
+func splitName(name string) (schema, object string) {
+	var inQuote bool
+	for i, r := range name {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case '.':
+			if !inQuote {
+				return unquote(name[:i]), unquote(name[i+1:])
+			}
+		}
+	}
+	return "", unquote(name)
+}
+
+func unquote(name string) string {
+	if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+		return name[1 : len(name)-1]
+	}
+	return strings.ToUpper(name)
+}
+
+// newConverter returns the converter for the column.
+func newConverter(col Column, dateFormats []string, loc *time.Location) converter {
+	typ := col.DataType
+	switch {
+	case typ == "NUMBER" && col.Scale == 0, typ == "INTEGER":
+		return func(s string) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer %q", s)
+			}
+			return i, nil
+		}
+	case typ == "NUMBER", typ == "FLOAT", strings.HasPrefix(typ, "BINARY_"):
+		return func(s string) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", s)
+			}
+			return f, nil
+		}
+	case typ == "DATE", strings.HasPrefix(typ, "TIMESTAMP"):
+		return func(s string) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			for _, layout := range dateFormats {
+				if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("invalid date %q", s)
+		}
+	case typ == "RAW", typ == "BLOB", typ == "LONG RAW":
+		return func(s string) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex %q", s)
+			}
+			return b, nil
+		}
+	}
+	return func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,546 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+// Package load loads CSV, TSV and JSON Lines data into Oracle tables,
+// with parallel sessions and array inserts.
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"gopkg.in/rana/ora.v4"
+)
+
+// Format is the format of the input.
+type Format uint8
+
+// Input formats.
+const (
+	CSV Format = iota
+	TSV
+	JSONLines
+)
+
+// ParseFormat returns the Format named csv, tsv, json or jsonl.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return CSV, nil
+	case "tsv", "tab":
+		return TSV, nil
+	case "json", "jsonl", "ndjson":
+		return JSONLines, nil
+	}
+	return CSV, fmt.Errorf("unknown format %q", s)
+}
+
+// Mode is the mode of the load.
+type Mode uint8
+
+// Load modes.
+const (
+	// Append inserts the rows.
+	Append Mode = iota
+	// Truncate truncates the table before inserting the rows.
+	Truncate
+	// Merge updates the rows with matching Keys, and inserts the others.
+	Merge
+)
+
+// Config is the configuration of Load.
+type Config struct {
+	// Table is the target table, as TABLE or SCHEMA.TABLE.
+	Table string
+	// Format of the input.
+	Format Format
+	// Comma is the field separator of CSV; the default is ','.
+	Comma rune
+	// Columns are the target columns of the fields of CSV and TSV records,
+	// or of the keys of the JSON objects.
+	// The default is the header record for CSV and TSV,
+	// and all the columns of the table for JSON Lines.
+	Columns []string
+	// Mode of the load.
+	Mode Mode
+	// Keys are the key columns for Merge.
+	Keys []string
+	// DateFormats are the time layouts the dates are parsed with, in order;
+	// the default is DefaultDateFormats.
+	DateFormats []string
+	// Location is the time zone of the dates without one; the default is time.Local.
+	Location *time.Location
+	// Encoding is the charset of the input; the default is UTF-8.
+	Encoding encoding.Encoding
+	// Workers is the number of parallel sessions; the default is GOMAXPROCS.
+	Workers int
+	// BatchSize is the number of rows inserted and committed at once;
+	// the default is 1024.
+	BatchSize int
+	// BadFile receives the rejected records with their errors,
+	// in the format of the input.
+	BadFile io.Writer
+	// MaxErrors stops the load after so many rejected records; 0 means no limit.
+	MaxErrors int
+}
+
+// Result is the result of Load.
+type Result struct {
+	// Rows is the number of loaded rows.
+	Rows int64
+	// Rejected is the number of rejected records.
+	Rejected int64
+}
+
+// ErrTooManyErrors is returned when Config.MaxErrors is exceeded.
+var ErrTooManyErrors = errors.New("too many errors")
+
+// record is an input record.
+type record struct {
+	line   int64
+	fields []string        // CSV and TSV
+	raw    json.RawMessage // JSON Lines
+	values []interface{}
+}
+
+// Load loads the data read from r into the table, with the sessions of pool,
+// and returns the number of loaded and rejected rows.
+//
+// Each batch is inserted (or merged) and committed by a worker, on its own
+// session. The records which can't be converted, or are rejected by the
+// database, are written to Config.BadFile.
+func Load(ctx context.Context, pool *ora.Pool, r io.Reader, cfg Config) (Result, error) {
+	var res Result
+	if cfg.Table == "" {
+		return res, errors.New("no table")
+	}
+	if cfg.Mode == Merge && len(cfg.Keys) == 0 {
+		return res, errors.New("Merge needs Keys")
+	}
+	if cfg.Comma == 0 {
+		cfg.Comma = ','
+	}
+	if cfg.Format == TSV {
+		cfg.Comma = '\t'
+	}
+	if len(cfg.DateFormats) == 0 {
+		cfg.DateFormats = DefaultDateFormats
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1024
+	}
+	if cfg.Encoding != nil {
+		r = cfg.Encoding.NewDecoder().Reader(r)
+	}
+
+	ses, err := pool.Get()
+	if err != nil {
+		return res, err
+	}
+	tableCols, err := Describe(ses, cfg.Table)
+	if err == nil && cfg.Mode == Truncate {
+		_, err = ses.PrepAndExe("TRUNCATE TABLE " + cfg.Table)
+	}
+	ses.Close()
+	if err != nil {
+		return res, err
+	}
+
+	keys, err := resolveKeys(cfg.Keys, tableCols)
+	if err != nil {
+		return res, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	l := &loader{cfg: cfg, keys: keys, res: &res, cancel: cancel}
+	l.bad = newBadWriter(cfg)
+	rr, err := l.newReader(r, tableCols)
+	if err != nil {
+		return res, err
+	}
+
+	batches := make(chan []record, cfg.Workers)
+	var wg sync.WaitGroup
+	errs := make(chan error, cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.work(pool, batches); err != nil {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+
+	err = l.read(ctx, rr, batches)
+	close(batches)
+	wg.Wait()
+	close(errs)
+	if werr := <-errs; werr != nil && (err == nil || err == context.Canceled) {
+		err = werr
+	}
+	if (err == nil || err == context.Canceled) && l.tooManyErrors() {
+		err = ErrTooManyErrors
+	}
+	if ferr := l.bad.flush(); err == nil {
+		err = ferr
+	}
+	return res, err
+}
+
+type loader struct {
+	cfg     Config
+	keys    []string // the quoted names of Config.Keys
+	columns []string
+	convs   []converter
+	res     *Result
+	bad     *badWriter
+	cancel  context.CancelFunc
+}
+
+// resolveKeys returns the quoted names of the key columns of the table,
+// to match the quoted column names of the loader.
+func resolveKeys(keys []string, tableCols []Column) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]bool, len(tableCols))
+	for _, c := range tableCols {
+		byName[c.Name] = true
+	}
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		name := unquote(strings.TrimSpace(k))
+		if !byName[name] {
+			return nil, fmt.Errorf("key column %q not found", k)
+		}
+		quoted[i] = `"` + name + `"`
+	}
+	return quoted, nil
+}
+
+// recordReader reads the next record, with the values converted.
+type recordReader func() (record, error)
+
+// newReader returns the reader of the records, and sets the columns and converters.
+func (l *loader) newReader(r io.Reader, tableCols []Column) (recordReader, error) {
+	byName := make(map[string]Column, len(tableCols))
+	for _, c := range tableCols {
+		byName[c.Name] = c
+	}
+	setColumns := func(names []string) error {
+		l.columns = make([]string, len(names))
+		l.convs = make([]converter, len(names))
+		for i, name := range names {
+			col, ok := byName[unquote(strings.TrimSpace(name))]
+			if !ok {
+				return fmt.Errorf("column %q not found in %s", name, l.cfg.Table)
+			}
+			l.columns[i] = `"` + col.Name + `"`
+			l.convs[i] = newConverter(col, l.cfg.DateFormats, l.cfg.Location)
+		}
+		return nil
+	}
+
+	var line int64
+	if l.cfg.Format == JSONLines {
+		names := l.cfg.Columns
+		if len(names) == 0 {
+			for _, c := range tableCols {
+				names = append(names, `"`+c.Name+`"`)
+			}
+		}
+		if err := setColumns(names); err != nil {
+			return nil, err
+		}
+		index := make(map[string]int, len(names))
+		for i, name := range names {
+			index[unquote(strings.TrimSpace(name))] = i
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 1<<20), 64<<20)
+		return func() (record, error) {
+			for scanner.Scan() {
+				line++
+				b := bytes.TrimSpace(scanner.Bytes())
+				if len(b) == 0 {
+					continue
+				}
+				rec := record{line: line, raw: append(json.RawMessage(nil), b...)}
+				return rec, l.convertJSON(&rec, index)
+			}
+			if err := scanner.Err(); err != nil {
+				return record{}, err
+			}
+			return record{}, io.EOF
+		}, nil
+	}
+
+	cr := csv.NewReader(bufio.NewReaderSize(r, 1<<20))
+	cr.Comma = l.cfg.Comma
+	cr.TrimLeadingSpace = true
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	names := l.cfg.Columns
+	if len(names) == 0 {
+		head, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("read header: %v", err)
+		}
+		line++
+		names = head
+	}
+	if err := setColumns(names); err != nil {
+		return nil, err
+	}
+	return func() (record, error) {
+		fields, err := cr.Read()
+		line++
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return record{}, err
+			}
+		}
+		rec := record{line: line, fields: fields}
+		if err != nil {
+			return rec, err
+		}
+		return rec, l.convertFields(&rec)
+	}, nil
+}
+
+func (l *loader) convertFields(rec *record) error {
+	if len(rec.fields) != len(l.convs) {
+		return fmt.Errorf("got %d fields, wanted %d", len(rec.fields), len(l.convs))
+	}
+	rec.values = make([]interface{}, len(l.convs))
+	for i, s := range rec.fields {
+		v, err := l.convs[i](s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", l.columns[i], err)
+		}
+		rec.values[i] = v
+	}
+	return nil
+}
+
+func (l *loader) convertJSON(rec *record, index map[string]int) error {
+	dec := json.NewDecoder(bytes.NewReader(rec.raw))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	rec.values = make([]interface{}, len(l.convs))
+	for k, v := range m {
+		i, ok := index[strings.ToUpper(k)]
+		if !ok {
+			if i, ok = index[k]; !ok {
+				continue
+			}
+		}
+		var s string
+		switch x := v.(type) {
+		case nil:
+			continue
+		case string:
+			s = x
+		case json.Number:
+			s = x.String()
+		case bool:
+			s = "0"
+			if x {
+				s = "1"
+			}
+		default:
+			b, err := json.Marshal(x)
+			if err != nil {
+				return err
+			}
+			s = string(b)
+		}
+		value, err := l.convs[i](s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+		rec.values[i] = value
+	}
+	return nil
+}
+
+// read reads the records, and sends them in batches.
+func (l *loader) read(ctx context.Context, rr recordReader, batches chan<- []record) error {
+	batch := make([]record, 0, l.cfg.BatchSize)
+	for {
+		rec, err := rr()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if rec.line == 0 {
+				return err
+			}
+			l.reject(rec, err)
+			if l.tooManyErrors() {
+				return ErrTooManyErrors
+			}
+			continue
+		}
+		batch = append(batch, rec)
+		if len(batch) < l.cfg.BatchSize {
+			continue
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		batch = make([]record, 0, l.cfg.BatchSize)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	select {
+	case batches <- batch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work loads the batches on its own session.
+func (l *loader) work(pool *ora.Pool, batches <-chan []record) error {
+	ses, err := pool.Get()
+	if err != nil {
+		return err
+	}
+	defer ses.Close()
+	opts := ora.CopyOptions{BatchSize: l.cfg.BatchSize}
+	if l.cfg.Mode == Merge {
+		opts.Keys = l.keys
+	}
+	rows := make([][]interface{}, 0, l.cfg.BatchSize)
+	for batch := range batches {
+		rows = rows[:0]
+		for _, rec := range batch {
+			rows = append(rows, rec.values)
+		}
+		tx, err := ses.StartTx()
+		if err != nil {
+			return err
+		}
+		// the rows rejected by the database are returned in res.Errors,
+		// an error fails the whole batch (such as a lost connection)
+		res, err := ses.CopyIn(l.cfg.Table, l.columns, ora.CopyFromRows(rows), opts)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		atomic.AddInt64(&l.res.Rows, res.Rows)
+		for _, re := range res.Errors {
+			l.reject(batch[re.Row], re.Err)
+		}
+		if l.tooManyErrors() {
+			l.cancel()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *loader) reject(rec record, err error) {
+	atomic.AddInt64(&l.res.Rejected, 1)
+	l.bad.write(rec, err)
+}
+
+func (l *loader) tooManyErrors() bool {
+	return l.cfg.MaxErrors > 0 && atomic.LoadInt64(&l.res.Rejected) >= int64(l.cfg.MaxErrors)
+}
+
+// badWriter writes the rejected records into the bad file.
+type badWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+	cw     *csv.Writer
+	err    error
+}
+
+func newBadWriter(cfg Config) *badWriter {
+	bw := &badWriter{w: cfg.BadFile, format: cfg.Format}
+	if bw.w != nil && cfg.Format != JSONLines {
+		bw.cw = csv.NewWriter(bw.w)
+		bw.cw.Comma = cfg.Comma
+	}
+	return bw
+}
+
+// write writes the record, with the line number and the error
+// as the last fields, or as "line" and "error" of a JSON object.
+func (bw *badWriter) write(rec record, err error) {
+	if bw.w == nil {
+		return
+	}
+	msg := strings.Replace(err.Error(), "\n", " ", -1)
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.err != nil {
+		return
+	}
+	if bw.cw != nil {
+		bw.err = bw.cw.Write(append(append(rec.fields[:len(rec.fields):len(rec.fields)],
+			fmt.Sprintf("line %d", rec.line)), msg))
+		return
+	}
+	var b []byte
+	if b, bw.err = json.Marshal(struct {
+		Line   int64           `json:"line"`
+		Error  string          `json:"error"`
+		Record json.RawMessage `json:"record,omitempty"`
+	}{rec.line, msg, validJSON(rec.raw)}); bw.err == nil {
+		_, bw.err = bw.w.Write(append(b, '\n'))
+	}
+}
+
+func (bw *badWriter) flush() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.cw != nil && bw.err == nil {
+		bw.cw.Flush()
+		bw.err = bw.cw.Error()
+	}
+	return bw.err
+}
+
+// validJSON returns raw if it is valid JSON, or else raw as a JSON string.
+func validJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || json.Valid(raw) {
+		return raw
+	}
+	b, _ := json.Marshal(string(raw))
+	return b
+}
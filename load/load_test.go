@@ -0,0 +1,159 @@
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testColumns = []Column{
+	{Name: "ID", DataType: "NUMBER", Scale: 0},
+	{Name: "AMOUNT", DataType: "NUMBER", Scale: -1},
+	{Name: "NAME", DataType: "VARCHAR2", Scale: -1},
+	{Name: "BORN", DataType: "DATE", Scale: -1},
+}
+
+func TestConverter(t *testing.T) {
+	for i, tc := range []struct {
+		col   Column
+		in    string
+		await interface{}
+		err   bool
+	}{
+		{col: testColumns[0], in: " 12 ", await: int64(12)},
+		{col: testColumns[0], in: "1.5", err: true},
+		{col: testColumns[0], in: ""},
+		{col: testColumns[1], in: "1,5", await: 1.5},
+		{col: testColumns[1], in: "x", err: true},
+		{col: testColumns[2], in: " a ", await: " a "},
+		{col: testColumns[2], in: ""},
+		{col: testColumns[3], in: "2017-03-04", await: time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{col: testColumns[3], in: "04/03/2017", err: true},
+		{col: Column{Name: "R", DataType: "RAW"}, in: "00ff", await: []byte{0, 255}},
+	} {
+		got, err := newConverter(tc.col, DefaultDateFormats, time.UTC)(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d. wanted error for %q, got %#v", i, tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d. %q: %v", i, tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.await) {
+			t.Errorf("%d. %q: got %#v, wanted %#v", i, tc.in, got, tc.await)
+		}
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	for _, tc := range [][3]string{
+		{"tbl", "", "TBL"},
+		{"scott.emp", "SCOTT", "EMP"},
+		{`"Scott"."a.b"`, "Scott", "a.b"},
+	} {
+		if schema, object := splitName(tc[0]); schema != tc[1] || object != tc[2] {
+			t.Errorf("%q: got %q.%q, wanted %q.%q", tc[0], schema, object, tc[1], tc[2])
+		}
+	}
+}
+
+func TestResolveKeys(t *testing.T) {
+	keys, err := resolveKeys([]string{"id", ` "NAME" `}, testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`"ID"`, `"NAME"`}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %q, wanted %q", keys, want)
+	}
+	if _, err = resolveKeys([]string{`"id"`}, testColumns); err == nil {
+		t.Error("wanted error for a missing key column")
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	var bad bytes.Buffer
+	cfg := Config{Table: "T", Comma: ';', BadFile: &bad, DateFormats: DefaultDateFormats, Location: time.UTC}
+	l := &loader{cfg: cfg, res: &Result{}}
+	l.bad = newBadWriter(cfg)
+	rr, err := l.newReader(strings.NewReader("id;name;born\n1;a;2017-03-04\nx;b;\n2;c\n3;;\n"), testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]interface{}
+	for {
+		rec, err := rr()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.reject(rec, err)
+			continue
+		}
+		got = append(got, rec.values)
+	}
+	await := [][]interface{}{
+		{int64(1), "a", time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{int64(3), nil, nil},
+	}
+	if !reflect.DeepEqual(got, await) {
+		t.Errorf("got %#v, wanted %#v", got, await)
+	}
+	if err = l.bad.flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(bad.String()), "\n")
+	if l.res.Rejected != 2 || len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "x;b;;line 3;") || !strings.HasPrefix(lines[1], "2;c;line 4;") {
+		t.Errorf("got %d rejected, bad file:\n%s", l.res.Rejected, bad.String())
+	}
+}
+
+func TestReadJSONLines(t *testing.T) {
+	var bad bytes.Buffer
+	cfg := Config{Table: "T", Format: JSONLines, BadFile: &bad, DateFormats: DefaultDateFormats, Location: time.UTC}
+	l := &loader{cfg: cfg, res: &Result{}}
+	l.bad = newBadWriter(cfg)
+	rr, err := l.newReader(strings.NewReader(`{"id": 1, "amount": 2.5, "name": "a", "other": true}
+
+{"ID": "2", "born": null}
+{"id": 3.5}
+not json
+`), testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]interface{}
+	for {
+		rec, err := rr()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.reject(rec, err)
+			continue
+		}
+		got = append(got, rec.values)
+	}
+	await := [][]interface{}{
+		{int64(1), 2.5, "a", nil},
+		{int64(2), nil, nil, nil},
+	}
+	if !reflect.DeepEqual(got, await) {
+		t.Errorf("got %#v, wanted %#v", got, await)
+	}
+	lines := strings.Split(strings.TrimSpace(bad.String()), "\n")
+	if l.res.Rejected != 2 || len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], `{"line":4,`) || !strings.Contains(lines[1], `"record":"not json"`) {
+		t.Errorf("got %d rejected, bad file:\n%s", l.res.Rejected, bad.String())
+	}
+}
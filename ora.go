@@ -43,7 +43,9 @@ func init() {
 	_drv.envPool = newPool(func() interface{} { return &Env{openSrvs: newSrvList(), openCons: newConList()} })
 	_drv.conPool = newPool(func() interface{} { return &Con{} })
 	_drv.srvPool = newPool(func() interface{} { return &Srv{openSess: newSesList()} })
-	_drv.sesPool = newPool(func() interface{} { return &Ses{openStmts: newStmtList(), openTxs: newTxList()} })
+	_drv.sesPool = newPool(func() interface{} {
+		return &Ses{openStmts: newStmtList(), openTxs: newTxList(), openSubscrs: newSubscrList(), openGlobalTxs: newGlobalTxList()}
+	})
 	_drv.stmtPool = newPool(func() interface{} { return &Stmt{openRsets: newRsetList()} })
 	_drv.txPool = newPool(func() interface{} { return &Tx{} })
 	_drv.rsetPool = newPool(func() interface{} { return &Rset{genByPool: true} })
@@ -175,27 +177,35 @@ func OpenEnv() (env *Env, err error) {
 	// OCI_DEFAULT  - The default value, which is non-UTF-16 encoding.
 	// OCI_THREADED - Uses threaded environment. Internal data structures not exposed to the user are protected from concurrent accesses by multiple threads.
 	// OCI_OBJECT   - Uses object features such as OCINumber, OCINumberToInt, OCINumberFromInt. These are used in oracle-go type conversions.
+	// OCI_EVENTS   - Receives the HA (FAN) event and change notifications, if cfg.Events.
+	mode := C.ub4(C.OCI_DEFAULT | C.OCI_OBJECT | C.OCI_THREADED)
+	if cfg.Events {
+		mode |= C.OCI_EVENTS
+	}
 	_drv.RLock()
 	env = _drv.envPool.Get().(*Env) // set *Env
 	env.cmu.Lock()
 	defer env.cmu.Unlock()
 	r := C.OCIEnvNlsCreate(
 		&env.ocienv, //OCIEnv        **envhpp,
-		C.OCI_DEFAULT|C.OCI_OBJECT|C.OCI_THREADED, //ub4           mode,
-		nil,  //void          *ctxp,
-		nil,  //void          *(*malocfp)
-		nil,  //void          *(*ralocfp)
-		nil,  //void          (*mfreefp)
-		0,    //size_t        xtramemsz,
-		nil,  //void          **usrmempp
-		csid, //ub2           charset,
-		csid) //ub2           ncharset );
+		mode,        //ub4           mode,
+		nil,         //void          *ctxp,
+		nil,         //void          *(*malocfp)
+		nil,         //void          *(*ralocfp)
+		nil,         //void          (*mfreefp)
+		0,           //size_t        xtramemsz,
+		nil,         //void          **usrmempp
+		csid,        //ub2           charset,
+		csid)        //ub2           ncharset );
 	_drv.RUnlock()
 	if r == C.OCI_ERROR {
 		return nil, errF("Unable to create environment handle (Return code = %d).", r)
 	}
 	ocierr, err := env.allocOciHandle(C.OCI_HTYPE_ERROR) // alloc oci error handle
 	if err != nil {
+		env.freeOciHandle(unsafe.Pointer(env.ocienv), C.OCI_HTYPE_ENV)
+		env.ocienv = nil
+		_drv.envPool.Put(env)
 		return nil, errE(err)
 	}
 
@@ -204,6 +214,18 @@ func OpenEnv() (env *Env, err error) {
 		env.id = _drv.envId.nextId()
 	}
 	env.SetCfg(cfg.StmtCfg)
+	if cfg.Events {
+		if err = env.setEvents(); err != nil {
+			env.freeOciHandle(unsafe.Pointer(env.ocierr), C.OCI_HTYPE_ERROR)
+			env.freeOciHandle(unsafe.Pointer(env.ocienv), C.OCI_HTYPE_ENV)
+			env.ocienv, env.ocierr = nil, nil
+			_drv.envPool.Put(env)
+			return nil, errE(err)
+		}
+		env.Lock()
+		env.events = true
+		env.Unlock()
+	}
 	_drv.RLock()
 	_drv.openEnvs.add(env)
 	_drv.RUnlock()
@@ -13,6 +13,10 @@ import (
 var (
 	tbls   = make(map[string]*tbl)
 	tblsMu sync.Mutex
+
+	// structTbls are the struct types of Rset.ScanStruct and Rset.All.
+	structTbls   = make(map[reflect.Type]*tbl)
+	structTblsMu sync.Mutex
 )
 
 // Schema may optionally be specified to prefix a table name in the sql
@@ -623,6 +627,25 @@ func AddTbl(v interface{}, tblName string) (err error) {
 	return nil
 }
 
+// structTbl returns the tbl of the struct type, for Rset.ScanStruct and Rset.All.
+// The type is not registered for the ORM functions.
+func structTbl(typ reflect.Type) (*tbl, error) {
+	structTblsMu.Lock()
+	t, ok := structTbls[typ]
+	structTblsMu.Unlock()
+	if ok {
+		return t, nil
+	}
+	t, err := tblParse(typ, "")
+	if err != nil {
+		return nil, err
+	}
+	structTblsMu.Lock()
+	structTbls[typ] = t
+	structTblsMu.Unlock()
+	return t, nil
+}
+
 func tblGet(v interface{}) (tbl *tbl, err error) {
 	defer func() {
 		if value := recover(); value != nil {
@@ -670,6 +693,17 @@ func finalValue(v interface{}) (rv reflect.Value, err error) {
 }
 
 func tblCreate(typ reflect.Type, tblName string) (t *tbl, err error) {
+	if t, err = tblParse(typ, tblName); err != nil {
+		return nil, err
+	}
+	tblsMu.Lock()
+	tbls[typ.Name()] = t // store tbl for future lookup
+	tblsMu.Unlock()
+	return t, nil
+}
+
+// tblParse returns the tbl of the struct type, from the `db` tags of its fields.
+func tblParse(typ reflect.Type, tblName string) (t *tbl, err error) {
 	if typ.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("Expected type of Struct, received type of %v.", typ.Kind())
 	}
@@ -777,9 +811,6 @@ Outer:
 	if len(t.cols) == 0 {
 		return nil, fmt.Errorf("Struct '%v' has no db columns.", typ.Name())
 	}
-	tblsMu.Lock()
-	tbls[typ.Name()] = t // store tbl for future lookup
-	tblsMu.Unlock()
 	return t, nil
 }
 
@@ -34,6 +34,43 @@ const (
 	DefaultEvictDuration = time.Minute
 )
 
+// PoolOption configures the session validation and health checking of a Pool.
+type PoolOption func(*poolOption)
+type poolOption struct {
+	maxLifetime   time.Duration
+	validateIdle  time.Duration
+	checkInterval time.Duration
+	minIdle       int
+
+	strategy  EndpointStrategy
+	endpoints []Endpoint
+	cooldown  time.Duration
+}
+
+// PoolMaxLifetime makes the Pool close the sessions (and their connections)
+// which were opened more than d ago, instead of reusing them.
+func PoolMaxLifetime(d time.Duration) PoolOption {
+	return func(o *poolOption) { o.maxLifetime = d }
+}
+
+// PoolValidateIdle makes the Pool Ping the sessions which were idle
+// for more than d, before handing them out.
+func PoolValidateIdle(d time.Duration) PoolOption {
+	return func(o *poolOption) { o.validateIdle = d }
+}
+
+// PoolHealthCheck starts a background health checker, which Pings
+// the idle sessions in every d, and drops the dead ones.
+func PoolHealthCheck(d time.Duration) PoolOption {
+	return func(o *poolOption) { o.checkInterval = d }
+}
+
+// PoolMinIdle makes the Pool open n sessions when created,
+// and the health checker keep at least n idle sessions.
+func PoolMinIdle(n int) PoolOption {
+	return func(o *poolOption) { o.minIdle = n }
+}
+
 // NewPool returns an idle session pool,
 // which evicts the idle sessions every minute,
 // and automatically manages the required new connections (Srv).
@@ -45,30 +82,61 @@ const (
 // usage on the server.
 //
 // If size <= 0, then DefaultPoolSize is used.
-func (env *Env) NewPool(srvCfg SrvCfg, sesCfg SesCfg, size int) *Pool {
+//
+// Sessions which got a disconnect error (such as ORA-03113) are dropped
+// when put back. See the PoolOptions for more validation.
+func (env *Env) NewPool(srvCfg SrvCfg, sesCfg SesCfg, size int, opts ...PoolOption) *Pool {
 	if srvCfg.IsZero() {
 		panic("srvCfg shall not be empty")
 	}
 	if size <= 0 {
 		size = DefaultPoolSize
 	}
+	if sesCfg.IsZero() {
+		sesCfg = NewSesCfg()
+		sesCfg.StmtCfg = Cfg().StmtCfg
+	}
 	p := &Pool{
+		id:     _drv.poolId.nextId(),
 		env:    env,
 		srvCfg: srvCfg, sesCfg: sesCfg,
 		srv: newIdlePool(size),
 		ses: newIdlePool(size),
 	}
+	p.opt.cooldown = DefaultEndpointCooldown
+	for _, opt := range opts {
+		opt(&p.opt)
+	}
+	p.endpoints = newEndpoints(p.opt.endpoints)
+	if p.opt.minIdle > size {
+		p.opt.minIdle = size
+	}
 	p.poolEvictor = &poolEvictor{
 		Evict: func(d time.Duration) {
 			p.ses.Evict(d)
 			p.srv.Evict(d)
 		}}
 	p.SetEvictDuration(DefaultEvictDuration)
+	if p.opt.minIdle > 0 {
+		if err := p.fill(p.opt.minIdle); err != nil {
+			_drv.Cfg().Log.Logger.Errorf("Pool warm-up: %v", err)
+		}
+	}
+	if p.opt.checkInterval > 0 {
+		p.stop = make(chan struct{})
+		go p.healthCheck(p.opt.checkInterval, p.stop)
+	}
+	env.RLock()
+	events := env.events
+	env.RUnlock()
+	if events {
+		eventPools.add(p.id, p)
+	}
 	return p
 }
 
 // NewPool returns a new session pool with default config.
-func NewPool(dsn string, size int) (*Pool, error) {
+func NewPool(dsn string, size int, opts ...PoolOption) (*Pool, error) {
 	env, err := OpenEnv()
 	if err != nil {
 		return nil, err
@@ -76,16 +144,24 @@ func NewPool(dsn string, size int) (*Pool, error) {
 	srvCfg := SrvCfg{StmtCfg: NewStmtCfg(), Pool: DSNPool(dsn)}
 	sesCfg := SesCfg{Mode: DSNMode(dsn)}
 	sesCfg.Username, sesCfg.Password, srvCfg.Dblink = SplitDSN(dsn)
-	return env.NewPool(srvCfg, sesCfg, size), nil
+	return env.NewPool(srvCfg, sesCfg, size, opts...), nil
 }
 
 type Pool struct {
+	rr uint64 // atomic round-robin counter, first for alignment
+
+	id     uint64
 	env    *Env
 	srvCfg SrvCfg
 	sesCfg SesCfg
+	opt    poolOption
 
 	sync.Mutex
-	srv, ses *idlePool
+	srv, ses  *idlePool
+	stop      chan struct{}
+	closed    bool
+	counters  poolCounters
+	endpoints []*endpoint
 
 	*poolEvictor
 }
@@ -99,6 +175,12 @@ func (p *Pool) Close() (err error) {
 	}()
 	p.Lock()
 	defer p.Unlock()
+	p.closed = true
+	eventPools.remove(p.id)
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
 	for {
 		x := p.ses.Get()
 		if x == nil {
@@ -131,42 +213,48 @@ func (p *Pool) Get() (ses *Ses, err error) {
 		if r := recover(); r != nil {
 			err = errR(r)
 		}
+		p.counters.get(err)
+		if err == nil {
+			p.endpointAdd(ses, 1)
+		}
 	}()
-	p.Lock()
-	defer p.Unlock()
-
 	// Instead of closing the session, put it back to the session pool.
 	Instead := func(ses *Ses) error {
 		if ses == nil {
 			return nil
 		}
-		ses.Lock()
-		ses.insteadClose = nil // one-shot
-		ses.Unlock()
-		// if the session is to be evicted, its srv should go to the srv pool.
-		p.ses.Put(sesSrvPB{Ses: ses, p: p.srv})
+		p.put(ses)
 		return nil
 	}
-	// try get session from the ses pool
+	// try get session from the ses pool;
+	// the session is validated without holding the pool lock.
+	now := time.Now()
 	for {
-		x := p.ses.Get()
+		p.Lock()
+		x := p.ses.GetWait()
+		p.Unlock()
 		if x == nil { // the ses pool is empty
 			break
 		}
-		ses = x.(sesSrvPB).Ses
+		pb := x.(sesSrvPB)
+		ses = pb.Ses
 		if ses == nil || !ses.IsOpen() {
 			continue
 		}
+		if !p.isUsable(pb, now, true) {
+			p.discard(pb)
+			continue
+		}
+		ses.Lock()
 		ses.insteadClose = Instead
+		ses.Unlock()
 		return ses, nil
 	}
 
+	p.Lock()
+	defer p.Unlock()
 	var srv *Srv
 	// try to get srv from the srv pool
-	if p.sesCfg.IsZero() {
-		p.sesCfg = NewSesCfg()
-		p.sesCfg.StmtCfg = Cfg().StmtCfg
-	}
 	for {
 		x := p.srv.Get()
 		if x == nil { // the srv pool is empty
@@ -183,6 +271,7 @@ func (p *Pool) Get() (ses *Ses, err error) {
 			continue
 		}
 		if ses, err = srv.OpenSes(p.sesCfg); err == nil {
+			atomic.AddUint64(&p.counters.opened, 1)
 			ses.insteadClose = Instead
 			return ses, nil
 		}
@@ -190,29 +279,130 @@ func (p *Pool) Get() (ses *Ses, err error) {
 	}
 
 	//fmt.Fprintf(os.Stderr, "POOL: create new srv!\n")
-	if srv, err = p.env.OpenSrv(p.srvCfg); err != nil {
+	if srv, err = p.openSrv(); err != nil {
 		return nil, err
 	}
 	if ses, err = srv.OpenSes(p.sesCfg); err != nil {
 		return nil, err
 	}
+	atomic.AddUint64(&p.counters.opened, 1)
 	ses.insteadClose = Instead
 	return ses, nil
 }
 
 // Put the session back to the session pool.
 // Ensure that on ses Close (eviction), srv is put back on the idle pool.
+//
+// Sessions with a lost connection, or older than the max lifetime are closed.
 func (p *Pool) Put(ses *Ses) {
 	if ses == nil || !ses.IsOpen() {
 		return
 	}
 	//fmt.Fprintf(os.Stderr, "POOL: put back ses\n")
-	p.ses.Put(sesSrvPB{Ses: ses, p: p.srv})
+	p.put(ses)
+}
+
+func (p *Pool) put(ses *Ses) {
+	p.counters.put()
+	p.endpointAdd(ses, -1)
+	ses.Lock()
+	ses.insteadClose = nil // one-shot
+	ses.Unlock()
+	pb := sesSrvPB{Ses: ses, p: p.srv, since: time.Now()}
+	if !p.isUsable(pb, pb.since, false) {
+		p.discard(pb)
+		return
+	}
+	// if the session is to be evicted, its srv should go to the srv pool.
+	p.ses.Put(pb)
+}
+
+// isUsable reports whether the idle session can be handed out.
+// The session is Pinged only if ping is true, and it's been idle
+// for longer than the PoolValidateIdle duration.
+func (p *Pool) isUsable(pb sesSrvPB, now time.Time, ping bool) bool {
+	if pb.Ses.isBad() {
+		return false
+	}
+	if d := p.opt.maxLifetime; d > 0 && now.Sub(pb.Ses.OpenedAt()) > d {
+		return false
+	}
+	if d := p.opt.validateIdle; ping && d > 0 && now.Sub(pb.since) > d {
+		return pb.Ses.Ping() == nil
+	}
+	return true
+}
+
+// discard closes the session, and its connection, too.
+func (p *Pool) discard(pb sesSrvPB) {
+	pb.Ses.RLock()
+	srv := pb.Ses.srv
+	pb.Ses.RUnlock()
+	pb.p = nil // do not put srv back to the pool
+	atomic.AddUint64(&p.counters.closed, 1)
+	pb.Close()
+	srv.Close()
+}
+
+// Stats returns the statistics of the Pool.
+func (p *Pool) Stats() PoolStats {
+	return p.counters.stats(p.ses, p.srv)
+}
+
+// fill opens new sessions till the pool has at least n idle sessions.
+func (p *Pool) fill(n int) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.closed {
+		return nil
+	}
+	for p.ses.Len() < n {
+		srv, err := p.openSrv()
+		if err != nil {
+			return err
+		}
+		ses, err := srv.OpenSes(p.sesCfg)
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		atomic.AddUint64(&p.counters.opened, 1)
+		p.ses.Put(sesSrvPB{Ses: ses, p: p.srv, since: time.Now()})
+	}
+	return nil
+}
+
+// healthCheck Pings the idle sessions in every d, drops the dead ones,
+// and refills the pool to have the minimum idle sessions, till stop is closed.
+func (p *Pool) healthCheck(d time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		p.ses.Check(func(c io.Closer) bool {
+			pb := c.(sesSrvPB)
+			if p.isUsable(pb, time.Now(), false) && pb.Ses.Ping() == nil {
+				return true
+			}
+			p.discard(pb)
+			return false
+		})
+		if p.opt.minIdle > 0 {
+			if err := p.fill(p.opt.minIdle); err != nil {
+				_drv.Cfg().Log.Logger.Errorf("Pool health check: %v", err)
+			}
+		}
+	}
 }
 
 type sesSrvPB struct {
 	*Ses
-	p *idlePool
+	p     *idlePool
+	since time.Time
 }
 
 // Close: after closing the session, put its srv into the pool,
@@ -249,9 +439,10 @@ func (env *Env) NewSrvPool(srvCfg SrvCfg, size int) *SrvPool {
 }
 
 type SrvPool struct {
-	env    *Env
-	srvCfg SrvCfg
-	srv    *idlePool
+	env      *Env
+	srvCfg   SrvCfg
+	srv      *idlePool
+	counters poolCounters
 
 	*poolEvictor
 }
@@ -263,13 +454,19 @@ func (p *SrvPool) Close() error {
 // Get a connection.
 func (p *SrvPool) Get() (*Srv, error) {
 	for {
-		x := p.srv.Get()
+		x := p.srv.GetWait()
 		if x == nil { // the pool is empty
 			break
 		}
+		p.counters.get(nil)
 		return x.(*Srv), nil
 	}
-	return p.env.OpenSrv(p.srvCfg)
+	srv, err := p.env.OpenSrv(p.srvCfg)
+	if err == nil {
+		atomic.AddUint64(&p.counters.opened, 1)
+	}
+	p.counters.get(err)
+	return srv, err
 }
 
 // Put the connection back to the idle pool.
@@ -277,9 +474,15 @@ func (p *SrvPool) Put(srv *Srv) {
 	if srv == nil || !srv.IsOpen() {
 		return
 	}
+	p.counters.put()
 	p.srv.Put(srv)
 }
 
+// Stats returns the statistics of the SrvPool.
+func (p *SrvPool) Stats() PoolStats {
+	return p.counters.stats(p.srv)
+}
+
 // NewSesPool returns a session pool, which evicts the idle sessions in every minute.
 // The pool holds at most size idle Ses.
 // If size is zero, DefaultPoolSize will be used.
@@ -295,9 +498,10 @@ func (srv *Srv) NewSesPool(sesCfg SesCfg, size int) *SesPool {
 }
 
 type SesPool struct {
-	srv    *Srv
-	sesCfg SesCfg
-	ses    *idlePool
+	srv      *Srv
+	sesCfg   SesCfg
+	ses      *idlePool
+	counters poolCounters
 
 	*poolEvictor
 }
@@ -309,17 +513,24 @@ func (p *SesPool) Close() error {
 // Get a session from an idle Srv.
 func (p *SesPool) Get() (*Ses, error) {
 	for {
-		x := p.ses.Get()
+		x := p.ses.GetWait()
 		if x == nil { // the pool is empty
 			break
 		}
 		ses := x.(*Ses)
 		if err := ses.Ping(); err == nil {
+			p.counters.get(nil)
 			return ses, nil
 		}
+		atomic.AddUint64(&p.counters.closed, 1)
 		ses.Close()
 	}
-	return p.srv.OpenSes(p.sesCfg)
+	ses, err := p.srv.OpenSes(p.sesCfg)
+	if err == nil {
+		atomic.AddUint64(&p.counters.opened, 1)
+	}
+	p.counters.get(err)
+	return ses, err
 }
 
 // Put the session back to the session pool.
@@ -327,9 +538,20 @@ func (p *SesPool) Put(ses *Ses) {
 	if ses == nil || !ses.IsOpen() {
 		return
 	}
+	p.counters.put()
+	if ses.isBad() {
+		atomic.AddUint64(&p.counters.closed, 1)
+		ses.Close()
+		return
+	}
 	p.ses.Put(ses)
 }
 
+// Stats returns the statistics of the SesPool.
+func (p *SesPool) Stats() PoolStats {
+	return p.counters.stats(p.ses)
+}
+
 type poolEvictor struct {
 	Evict func(time.Duration)
 
@@ -455,6 +677,8 @@ const poolWaitPut = 1 * time.Second
 // The backing store is a simple []io.Closer, which is treated as random store,
 // to achive uniform reuse.
 type idlePool struct {
+	waitCount, waitNanos, evicted, closed uint64 // atomic counters
+
 	sync.RWMutex
 	elems atomic.Value
 }
@@ -492,6 +716,7 @@ func (p *idlePool) Evict(dur time.Duration) {
 				return
 			}
 			if elem != nil {
+				atomic.AddUint64(&p.evicted, 1)
 				elem.Close()
 			}
 		default:
@@ -500,18 +725,79 @@ func (p *idlePool) Evict(dur time.Duration) {
 	}
 }
 
-// Get returns a closer or nil, if no pool found.
+// Len returns the number of idle elements.
+func (p *idlePool) Len() int {
+	p.RLock()
+	defer p.RUnlock()
+	return len(p.Elems())
+}
+
+// Check takes out each idle element once, and puts it back if keep returns true.
+// Elements for which keep returns false are left for keep to close.
+func (p *idlePool) Check(keep func(io.Closer) bool) {
+	p.RLock()
+	elems := p.Elems()
+	n := len(elems)
+	p.RUnlock()
+	for i := 0; i < n; i++ {
+		var elem io.Closer
+		p.RLock()
+		select {
+		case elem = <-p.Elems():
+		default:
+		}
+		p.RUnlock()
+		if elem == nil {
+			return
+		}
+		if keep(elem) {
+			p.Put(elem)
+		}
+	}
+}
+
+// Get returns an idle closer, or nil if there is none.
 func (p *idlePool) Get() io.Closer {
 	p.RLock()
 	defer p.RUnlock()
 	for {
-		elems := p.Elems()
 		select {
-		case elem := <-elems:
+		case elem, ok := <-p.Elems():
+			if !ok {
+				return nil
+			}
+			if elem != nil {
+				return elem
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// GetWait returns an idle closer; if there is none, it waits a bit for one
+// to be put back, and returns nil if none arrives.
+// The waits are counted in the statistics.
+func (p *idlePool) GetWait() io.Closer {
+	if elem := p.Get(); elem != nil {
+		return elem
+	}
+	p.RLock()
+	defer p.RUnlock()
+	atomic.AddUint64(&p.waitCount, 1)
+	start := time.Now()
+	defer func() { atomic.AddUint64(&p.waitNanos, uint64(time.Since(start))) }()
+	timeout := time.After(poolWaitGet)
+	for {
+		select {
+		case elem, ok := <-p.Elems():
+			if !ok {
+				return nil
+			}
 			if elem != nil {
 				return elem
 			}
-		case <-time.After(poolWaitGet):
+		case <-timeout:
 			return nil
 		}
 	}
@@ -533,6 +819,7 @@ func (p *idlePool) Put(c io.Closer) {
 			case p.Elems() <- c:
 				return
 			case <-time.After(poolWaitPut):
+				atomic.AddUint64(&p.closed, 1)
 				c.Close()
 			}
 		}()
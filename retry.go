@@ -0,0 +1,138 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql/driver"
+	"math/rand"
+	"time"
+)
+
+// RetryOptions configures RetryTx.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts.
+	//
+	// The default is 5.
+	MaxAttempts int
+
+	// MaxElapsed is the time budget of the retries: no new attempt is
+	// started after MaxElapsed passed since the first one.
+	//
+	// The default is 0, which means no limit.
+	MaxElapsed time.Duration
+
+	// InitialBackoff is the wait before the second attempt, doubled
+	// before each further attempt, up to MaxBackoff.
+	// A random jitter of at most the same size is added to each wait.
+	//
+	// The defaults are 50ms and 5s.
+	InitialBackoff, MaxBackoff time.Duration
+
+	// TxOptions are passed to Ses.StartTx.
+	TxOptions []TxOption
+
+	// IsRetryable decides whether the failed attempt should be retried.
+	//
+	// The default is IsRetryable.
+	IsRetryable func(error) bool
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = 50 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Second
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	if o.IsRetryable == nil {
+		o.IsRetryable = IsRetryable
+	}
+	return o
+}
+
+// IsRetryable reports whether the transaction failed with err may succeed
+// when retried: it is true for the errors meaning that the connection is lost,
+// and for
+//
+//	ORA-00060: deadlock detected while waiting for resource
+//	ORA-08177: can't serialize access for this transaction
+//	ORA-04068: existing state of packages has been discarded
+//	ORA-04061: existing state of ... has been invalidated
+//	ORA-04065: not executed, altered or dropped ...
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == driver.ErrBadConn || isBadConnErr(err) {
+		return true
+	}
+	return errCode(err) == 8177 ||
+		isErr(err, ErrDeadlock) || isErr(err, ErrPackageStateDiscarded)
+}
+
+// RetryTx runs f in a transaction on a session of the pool, and commits it.
+//
+// If f or the commit fails with an error for which opts.IsRetryable returns
+// true, the transaction is rolled back, and after an exponential backoff
+// with jitter, f is run again, in a new transaction.
+// A session with a lost connection is dropped from the pool,
+// so the next attempt gets a new connection.
+//
+// Returns the number of attempts and the last error.
+func RetryTx(ctx context.Context, pool *Pool, opts RetryOptions, f func(*Tx) error) (attempts int, err error) {
+	opts = opts.withDefaults()
+	return retry(ctx, opts, func() error {
+		ses, err := pool.Get()
+		if err != nil {
+			return err
+		}
+		defer ses.Close() // puts back into the pool, or drops if it is bad
+		tx, err := ses.StartTxContext(ctx, opts.TxOptions...)
+		if err != nil {
+			return ses.markBad(err)
+		}
+		if err = f(tx); err == nil {
+			err = tx.CommitContext(ctx)
+		} else {
+			tx.Rollback()
+		}
+		return ses.markBad(err)
+	})
+}
+
+// retry calls f till it succeeds, or the attempts or the time budget
+// of opts is exhausted, or f returns a non-retryable error, or ctx is done.
+func retry(ctx context.Context, opts RetryOptions, f func() error) (attempts int, err error) {
+	var deadline time.Time
+	if opts.MaxElapsed > 0 {
+		deadline = time.Now().Add(opts.MaxElapsed)
+	}
+	backoff := opts.InitialBackoff
+	for {
+		attempts++
+		if err = f(); err == nil || !opts.IsRetryable(err) || attempts >= opts.MaxAttempts {
+			return attempts, err
+		}
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)+1))
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return attempts, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempts, err
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
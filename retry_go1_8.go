@@ -0,0 +1,34 @@
+// +build go1.8
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RetryDBTx is like RetryTx, but for database/sql: runs f in a transaction
+// started with txOpts, and commits it, retrying it on a retryable error.
+//
+// A connection which is lost is dropped by database/sql, as the driver
+// returns driver.ErrBadConn for it.
+//
+// opts.TxOptions is not used.
+func RetryDBTx(ctx context.Context, db *sql.DB, txOpts *sql.TxOptions, opts RetryOptions, f func(*sql.Tx) error) (attempts int, err error) {
+	opts = opts.withDefaults()
+	return retry(ctx, opts, func() error {
+		tx, err := db.BeginTx(ctx, txOpts)
+		if err != nil {
+			return err
+		}
+		if err = f(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
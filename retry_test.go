@@ -0,0 +1,75 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRetry tests the attempt counting and give up conditions of retry.
+func TestRetry(t *testing.T) {
+	deadlock := &ORAError{code: 60}
+	fatal := errors.New("fatal")
+	opts := RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond}.withDefaults()
+
+	for i, tc := range []struct {
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{errs: []error{nil}, attempts: 1},
+		{errs: []error{deadlock, nil}, attempts: 2},
+		{errs: []error{deadlock, fatal, nil}, attempts: 2, err: fatal},
+		{errs: []error{deadlock, deadlock, deadlock, nil}, attempts: 3, err: deadlock},
+	} {
+		var n int
+		attempts, err := retry(context.Background(), opts, func() error {
+			n++
+			return tc.errs[n-1]
+		})
+		if attempts != tc.attempts || err != tc.err {
+			t.Errorf("%d. got %d, %v; wanted %d, %v", i, attempts, err, tc.attempts, tc.err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if attempts, err := retry(ctx, opts, func() error { return deadlock }); attempts != 1 || err != deadlock {
+		t.Errorf("canceled: got %d, %v; wanted 1, %v", attempts, err, deadlock)
+	}
+
+	opts.MaxElapsed = time.Nanosecond
+	if attempts, _ := retry(context.Background(), opts, func() error { return deadlock }); attempts != 1 {
+		t.Errorf("budget: got %d attempts, wanted 1", attempts)
+	}
+}
+
+// TestBadConnCodes tests that the lost connection errors are treated the
+// same by database/sql, the pools and IsRetryable.
+func TestBadConnCodes(t *testing.T) {
+	for _, code := range badConnCodes {
+		err := &ORAError{caller: methodInfo{"Stmt", "exe"}, err: &ORAError{code: code}}
+		if !isErr(err, ErrConnectionLost) {
+			t.Errorf("ORA-%05d: not ErrConnectionLost", code)
+		}
+		if got := maybeBadConn(err); got != driver.ErrBadConn {
+			t.Errorf("ORA-%05d: got %v, wanted driver.ErrBadConn", code, got)
+		}
+		var ses Ses
+		if ses.markBad(err); !ses.isBad() {
+			t.Errorf("ORA-%05d: the session is not dropped", code)
+		}
+		if !IsRetryable(err) {
+			t.Errorf("ORA-%05d: not retryable", code)
+		}
+	}
+	if err := (&ORAError{code: 1}); maybeBadConn(err) == driver.ErrBadConn || IsRetryable(err) {
+		t.Errorf("ORA-00001 is not a lost connection")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Router routes the read-only work to the replica Pools (such as Active Data Guard standbys),
+// and everything else to the primary Pool.
+//
+// If no replica is healthy, the read-only work goes to the primary, too.
+// A replica failing to give a session is skipped for DefaultEndpointCooldown.
+//
+// The sessions got from the Router shall be Closed, to put them back to their Pool.
+type Router struct {
+	rr uint64 // atomic round-robin counter, first for alignment
+
+	primary  *Pool
+	replicas []*Pool
+	failedAt []int64 // atomic, UnixNano
+}
+
+// NewRouter returns a Router, routing between the primary and the replica Pools.
+func NewRouter(primary *Pool, replicas ...*Pool) *Router {
+	if primary == nil {
+		panic("primary pool shall not be nil")
+	}
+	return &Router{
+		primary:  primary,
+		replicas: replicas,
+		failedAt: make([]int64, len(replicas)),
+	}
+}
+
+// Close closes the primary and the replica Pools.
+func (r *Router) Close() error {
+	err := r.primary.Close()
+	for _, p := range r.replicas {
+		if closeErr := p.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Get returns a session from the primary.
+func (r *Router) Get() (*Ses, error) {
+	return r.primary.Get()
+}
+
+// GetReadOnly returns a session from a healthy replica,
+// or from the primary, if no replica is healthy.
+func (r *Router) GetReadOnly() (*Ses, error) {
+	n := len(r.replicas)
+	if n == 0 {
+		return r.primary.Get()
+	}
+	now := time.Now()
+	first := int(atomic.AddUint64(&r.rr, 1) % uint64(n))
+	for i := 0; i < n; i++ {
+		j := (first + i) % n
+		if t := atomic.LoadInt64(&r.failedAt[j]); t != 0 && now.Sub(time.Unix(0, t)) < DefaultEndpointCooldown {
+			continue
+		}
+		ses, err := r.replicas[j].Get()
+		if err == nil && !ses.isBad() {
+			atomic.StoreInt64(&r.failedAt[j], 0)
+			return ses, nil
+		}
+		if ses != nil {
+			ses.Close()
+		}
+		atomic.StoreInt64(&r.failedAt[j], now.UnixNano())
+		_drv.Cfg().Log.Logger.Errorf("Router replica %d: %v", j, err)
+	}
+	return r.primary.Get()
+}
+
+// GetForTx returns a session for starting a transaction with the given options:
+// from a replica for a read-only (TxReadOnly) transaction, from the primary otherwise.
+func (r *Router) GetForTx(opts ...TxOption) (*Ses, error) {
+	var o txOption
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.flags&TxReadOnly != 0 {
+		return r.GetReadOnly()
+	}
+	return r.primary.Get()
+}
+
+// GetFor returns a session for executing the given statement:
+// from a replica for a plain query (see isReadOnlySQL), from the primary otherwise.
+func (r *Router) GetFor(sql string) (*Ses, error) {
+	if len(r.replicas) == 0 || !isReadOnlySQL(sql) {
+		return r.primary.Get()
+	}
+	return r.GetReadOnly()
+}
+
+// isReadOnlySQL reports whether sql is a plain query which may run on a replica:
+// a SELECT or WITH statement without FOR UPDATE.
+// Comments and the text of literals and quoted identifiers are skipped.
+func isReadOnlySQL(sql string) bool {
+	var words []string
+	for i := 0; i < len(sql); {
+		switch c := sql[i]; {
+		case strings.HasPrefix(sql[i:], "--"):
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			if j := strings.Index(sql[i+2:], "*/"); j >= 0 {
+				i += 2 + j + 2
+			} else {
+				i = len(sql)
+			}
+		case c == '\'' || c == '"':
+			if j := strings.IndexByte(sql[i+1:], c); j >= 0 {
+				i += 1 + j + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(sql) && (sql[j] == '_' || sql[j] == '$' || sql[j] == '#' ||
+				sql[j] >= 'a' && sql[j] <= 'z' || sql[j] >= 'A' && sql[j] <= 'Z' || sql[j] >= '0' && sql[j] <= '9') {
+				j++
+			}
+			words = append(words, strings.ToUpper(sql[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+	if len(words) == 0 || words[0] != "SELECT" && words[0] != "WITH" {
+		return false
+	}
+	for i := 1; i < len(words); i++ {
+		if words[i] == "UPDATE" && words[i-1] == "FOR" {
+			return false
+		}
+	}
+	return true
+}
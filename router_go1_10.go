@@ -0,0 +1,193 @@
+// +build go1.10
+
+// Copyright 2017 Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+)
+
+var (
+	_ = driver.Connector((*Router)(nil))
+
+	_ = driver.Conn((*routerCon)(nil))
+	_ = driver.ConnBeginTx((*routerCon)(nil))
+	_ = driver.ConnPrepareContext((*routerCon)(nil))
+	_ = driver.Pinger((*routerCon)(nil))
+)
+
+// Connect returns a connection for database/sql, which routes
+// read-only transactions and standalone SELECTs to the replicas,
+// everything else to the primary.
+//
+// Use it as sql.OpenDB(router).
+func (r *Router) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &routerCon{r: r}, nil
+}
+
+// Driver returns the ora driver.
+func (r *Router) Driver() driver.Driver { return _drv }
+
+// routerCon is a driver.Conn which gets its primary and replica sessions lazily.
+type routerCon struct {
+	r *Router
+
+	mu               sync.Mutex
+	primary, replica *Ses
+	tx               *Ses // the session of the running transaction
+}
+
+func (c *routerCon) getPrimary() (*Ses, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.primary == nil || c.primary.isBad() {
+		if c.primary != nil {
+			c.primary.Close()
+		}
+		ses, err := c.r.Get()
+		if err != nil {
+			return nil, maybeBadConn(err)
+		}
+		c.primary = ses
+	}
+	return c.primary, nil
+}
+
+func (c *routerCon) getReplica() (*Ses, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replica == nil || c.replica.isBad() {
+		if c.replica != nil {
+			c.replica.Close()
+		}
+		ses, err := c.r.GetReadOnly()
+		if err != nil {
+			return nil, maybeBadConn(err)
+		}
+		c.replica = ses
+	}
+	return c.replica, nil
+}
+
+// Prepare readies a sql string for use.
+func (c *routerCon) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares the query on the session of the running transaction,
+// or on a replica if it is a plain query (see isReadOnlySQL), or on the primary.
+func (c *routerCon) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	ses := c.tx
+	c.mu.Unlock()
+	if ses == nil && len(c.r.replicas) != 0 && isReadOnlySQL(query) {
+		var err error
+		if ses, err = c.getReplica(); err != nil {
+			return nil, err
+		}
+	}
+	if ses == nil {
+		var err error
+		if ses, err = c.getPrimary(); err != nil {
+			return nil, err
+		}
+	}
+	stmt, err := ses.Prep(query)
+	if err != nil {
+		return nil, maybeBadConn(err)
+	}
+	return &DrvStmt{stmt: stmt}, nil
+}
+
+// Begin starts a transaction on the primary.
+func (c *routerCon) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction on a replica if opts.ReadOnly, on the primary otherwise.
+func (c *routerCon) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	flags, err := txFlags(opts)
+	if err != nil {
+		return nil, err
+	}
+	var ses *Ses
+	if opts.ReadOnly {
+		ses, err = c.getReplica()
+	} else {
+		ses, err = c.getPrimary()
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx, err := startTxContext(ctx, ses, flags)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tx = ses
+	c.mu.Unlock()
+	return routerTx{Tx: tx, c: c}, nil
+}
+
+// Ping pings the primary.
+func (c *routerCon) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ses, err := c.getPrimary()
+	if err != nil {
+		return err
+	}
+	return maybeBadConn(ses.Ping())
+}
+
+// Close puts back the sessions to their Pools.
+func (c *routerCon) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, ses := range []*Ses{c.primary, c.replica} {
+		if ses == nil {
+			continue
+		}
+		if closeErr := ses.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	c.primary, c.replica, c.tx = nil, nil, nil
+	return err
+}
+
+// routerTx ends the routing to the transaction's session on Commit and Rollback.
+type routerTx struct {
+	*Tx
+	c *routerCon
+}
+
+func (tx routerTx) Commit() error {
+	defer tx.end()
+	return tx.Tx.Commit()
+}
+func (tx routerTx) Rollback() error {
+	defer tx.end()
+	return tx.Tx.Rollback()
+}
+func (tx routerTx) end() {
+	tx.c.mu.Lock()
+	tx.c.tx = nil
+	tx.c.mu.Unlock()
+}
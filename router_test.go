@@ -0,0 +1,30 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import "testing"
+
+func TestIsReadOnlySQL(t *testing.T) {
+	for _, tc := range []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM dual", true},
+		{"  select 1 from dual", true},
+		{"/* hint */ WITH x AS (SELECT 1 a FROM dual) SELECT a FROM x", true},
+		{"-- comment\nSELECT 'for update' FROM dual", true},
+		{`SELECT "FOR" FROM t`, true},
+		{"SELECT * FROM emp FOR UPDATE", false},
+		{"select * from emp for\n update nowait", false},
+		{"INSERT INTO t SELECT * FROM dual", false},
+		{"BEGIN SELECT 1 INTO :x FROM dual; END;", false},
+		{"DECLARE x NUMBER; BEGIN UPDATE t SET a = 1; END;", false},
+		{"", false},
+	} {
+		if got := isReadOnlySQL(tc.sql); got != tc.want {
+			t.Errorf("%q: got %t, wanted %t", tc.sql, got, tc.want)
+		}
+	}
+}
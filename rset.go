@@ -10,6 +10,7 @@ package ora
 import "C"
 import (
 	"container/list"
+	"context"
 	"fmt"
 	"io"
 	"sync"
@@ -99,14 +100,6 @@ type Rset struct {
 	sysNamer
 }
 
-type Column struct {
-	Name      string
-	Type      C.ub2
-	Length    uint32
-	Precision C.sb2
-	Scale     C.sb1
-}
-
 // Err returns the last error of the reesult set.
 func (rset *Rset) Err() error {
 	rset.RLock()
@@ -235,6 +228,10 @@ func (rset *Rset) beginRow() (err error) {
 	}
 
 	rset.finished = false
+	rset.stmt.RLock()
+	ses := rset.stmt.ses
+	rset.stmt.RUnlock()
+	stopTimeout := ses.startCallTimeout(rset.stmt.Cfg().CallTimeout())
 	// fetch rset.fetchLen rows
 	r := C.OCIStmtFetch2(
 		rset.ocistmt,         //OCIStmt     *stmthp,
@@ -244,9 +241,11 @@ func (rset *Rset) beginRow() (err error) {
 		C.sb4(0),             //sb4         fetchOffset,
 		C.OCI_DEFAULT)        //ub4         mode );
 	if r == C.OCI_ERROR {
-		err := env.ociError()
-		return err
-	} else if r == C.OCI_NO_DATA {
+		_stats.fetch(0)
+		return stopTimeout(ses.markBad(env.ociError()))
+	}
+	stopTimeout(nil)
+	if r == C.OCI_NO_DATA {
 		rset.log(_drv.Cfg().Log.Rset.BeginRow, "OCI_NO_DATA")
 		rset.finished = true
 		fetchLen := rset.fetchLen
@@ -266,6 +265,7 @@ func (rset *Rset) beginRow() (err error) {
 	}
 
 	rset.fetched = int64(rowsFetched)
+	_stats.fetch(rset.fetched)
 	rset.offset = 0
 	err = nil
 	if rset.fetched == 0 {
@@ -278,6 +278,36 @@ func (rset *Rset) beginRow() (err error) {
 	return err
 }
 
+// needFetch reports whether the next beginRow has to fetch from the server.
+func (rset *Rset) needFetch() bool {
+	rset.RLock()
+	defer rset.RUnlock()
+	return !rset.finished && !(rset.fetched > 0 && rset.fetched > rset.offset)
+}
+
+// cancel cancels the cursor on the server, by fetching zero rows.
+func (rset *Rset) cancel() error {
+	rset.Lock()
+	defer rset.Unlock()
+	if rset.ocistmt == nil || rset.env == nil || rset.finished {
+		return nil
+	}
+	rset.finished = true
+	rset.fetched, rset.offset = 0, 0
+	env := rset.env
+	r := C.OCIStmtFetch2(
+		rset.ocistmt,     //OCIStmt     *stmthp,
+		env.ocierr,       //OCIError    *errhp,
+		C.ub4(0),         //ub4         nrows,
+		C.OCI_FETCH_NEXT, //ub2         orientation,
+		C.sb4(0),         //sb4         fetchOffset,
+		C.OCI_DEFAULT)    //ub4         mode );
+	if r == C.OCI_ERROR {
+		return errE(env.ociError())
+	}
+	return nil
+}
+
 // endRow deallocates a handle for each column.
 func (rset *Rset) endRow() {
 	rset.log(_drv.Cfg().Log.Rset.EndRow)
@@ -376,6 +406,27 @@ func (rset *Rset) Next() bool {
 	return true
 }
 
+// NextContext is like Next, but Breaks the fetch when ctx is done,
+// and sets Err to ctx.Err().
+func (rset *Rset) NextContext(ctx context.Context) bool {
+	var ok bool
+	rset.RLock()
+	stmt := rset.stmt
+	rset.RUnlock()
+	err := runCtx(ctx, stmt.getSes(), func() error {
+		ok = rset.Next()
+		return nil
+	})
+	if err != nil {
+		rset.Lock()
+		rset.err = err
+		rset.Row = nil
+		rset.Unlock()
+		return false
+	}
+	return ok
+}
+
 // NextRow attempts to load a row from the Oracle buffer and return the row.
 // Nil is returned when there's no data.
 //
@@ -478,7 +529,7 @@ func (rset *Rset) open(stmt *Stmt, ocistmt *C.OCIStmt) error {
 		}
 	}()
 
-	var gct GoColumnType
+	d := describer{env: env}
 	for n := range defs {
 		// Create oci parameter handle; may be freed by OCIDescriptorFree()
 		// parameter position is 1-based
@@ -491,29 +542,10 @@ func (rset *Rset) open(stmt *Stmt, ocistmt *C.OCIStmt) error {
 		if r == C.OCI_ERROR {
 			return env.ociError()
 		}
-		ocipar := params[n].param
-		// Get column size in bytes
-		err = rset.paramAttr(ocipar, unsafe.Pointer(&params[n].columnSize), nil, C.OCI_ATTR_DATA_SIZE)
-		if err != nil {
-			return err
-		}
-		// Get oci data type code
-		err = rset.paramAttr(ocipar, unsafe.Pointer(&params[n].typeCode), nil, C.OCI_ATTR_DATA_TYPE)
-		if err != nil {
-			return err
-		}
-		// Get column name
-		var columnName *C.char
-		var colSize C.ub4
-		err := rset.paramAttr(ocipar, unsafe.Pointer(&columnName), &colSize, C.OCI_ATTR_NAME)
-		if err != nil {
+		if Columns[n], err = d.column(params[n].param, false); err != nil {
 			return err
 		}
-		Columns[n] = Column{
-			Name:   C.GoStringN(columnName, C.int(colSize)),
-			Type:   params[n].typeCode,
-			Length: params[n].columnSize,
-		}
+		params[n].typeCode, params[n].columnSize = C.ub2(Columns[n].Type), Columns[n].Length
 		rset.logF(logCfg.Rset.OpenDefs, "%d. %s/%d", n+1, Columns[n].Name, params[n].typeCode)
 	}
 
@@ -531,33 +563,31 @@ Loop:
 	rset.defs, rset.Columns, rset.Row = defs, Columns, Row
 	rset.fetchLen = fetchLen
 
-	cfg := rset.stmt.Cfg()
-	//rset.logF(logCfg.Rset.Open, "cfg=%#v", cfg)
 	stmt.RLock()
 	gcts := stmt.gcts
 	stmt.RUnlock()
+	return rset.defineColumns(gcts)
+}
+
+// defineColumns defines the select-list columns, with the GoColumnTypes
+// of gcts, or the defaults of the configuration. rset must be locked.
+func (rset *Rset) defineColumns(gcts []GoColumnType) error {
+	logCfg := _drv.Cfg().Log
+	stmt := rset.stmt
+	cfg := stmt.Cfg()
+	//rset.logF(logCfg.Rset.Open, "cfg=%#v", cfg)
+	defs := rset.defs
+	var err error
 	for n := range defs {
-		ocipar := params[n].param
-		ociTypeCode := params[n].typeCode
-		columnSize := params[n].columnSize
+		ociTypeCode := C.ub2(rset.Columns[n].Type)
+		columnSize := rset.Columns[n].Length
+		// intervals, BFILE, ROWID and cursors don't have a GoColumnType
+		gct := D
 
 		switch ociTypeCode {
 		case C.SQLT_NUM, C.SQLT_INT: // TimesTen may return an SQLT_INT
 			// NUMBER
-			// Get precision
-			var precision C.sb2
-			err = rset.paramAttr(ocipar, unsafe.Pointer(&precision), nil, C.OCI_ATTR_PRECISION)
-			if err != nil {
-				return err
-			}
-			// Get scale (the number of decimal places)
-			var scale C.sb1
-			err = rset.paramAttr(ocipar, unsafe.Pointer(&scale), nil, C.OCI_ATTR_SCALE)
-			if err != nil {
-				return err
-			}
-			rset.Columns[n].Precision = precision
-			rset.Columns[n].Scale = scale
+			precision, scale := rset.Columns[n].Precision, rset.Columns[n].Scale
 			if gcts == nil || n >= len(gcts) || gcts[n] == D {
 				gct = cfg.numericColumnType(int(precision), int(scale))
 			} else {
@@ -858,6 +888,7 @@ Loop:
 		default:
 			return errF("unsupported select-list column type (ociTypeCode: %v)", ociTypeCode)
 		}
+		rset.Columns[n].GoType = gct
 	}
 
 	return nil
@@ -940,25 +971,6 @@ func (rset *Rset) defineNumeric(n int, gct GoColumnType) (def, error) {
 	}).define(n+1, nullable, rset)
 }
 
-// paramAttr gets an attribute from the parameter handle.
-func (rset *Rset) paramAttr(ocipar *C.OCIParam, attrup unsafe.Pointer, attrSizep *C.ub4, attrType C.ub4) error {
-	if attrSizep == nil {
-		attrSizep = new(C.ub4)
-	}
-	env := rset.env
-	r := C.OCIAttrGet(
-		unsafe.Pointer(ocipar), //const void     *trgthndlp,
-		C.OCI_DTYPE_PARAM,      //ub4            trghndltyp,
-		attrup,                 //void           *attributep,
-		attrSizep,              //ub4            *sizep,
-		attrType,               //ub4            attrtype,
-		env.ocierr)             //OCIError       *errhp );
-	if r == C.OCI_ERROR {
-		return env.ociError()
-	}
-	return nil
-}
-
 // attr gets an attribute from the statement handle.
 func (rset *Rset) attr(attrup unsafe.Pointer, attrSize C.ub4, attrType C.ub4) error {
 	env := rset.env
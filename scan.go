@@ -0,0 +1,388 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/rana/ora.v4/num"
+)
+
+// Scan copies the columns of the current row into the values pointed at by dest,
+// converting them as database/sql Rows.Scan does.
+//
+// The Ora types (Int64, String, ...) are NULL (nil) when IsNull, else their Value.
+func (rset *Rset) Scan(dest ...interface{}) error {
+	rset.RLock()
+	row, cols := rset.Row, rset.Columns
+	rset.RUnlock()
+	if len(dest) != len(row) {
+		return errF("expected %d destination arguments in Scan, not %d", len(row), len(dest))
+	}
+	for i, v := range row {
+		if err := convertAssign(dest[i], v); err != nil {
+			return errF("column %d (%s): %v", i+1, cols[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// ScanStruct copies the columns of the current row into the fields of the
+// struct pointed at by ptr, as Scan.
+//
+// Columns are mapped to the exported fields by the `db:"column_name"` tag,
+// or else by the case-insensitive name of the field, as with Sel.
+// Columns without a field, and fields without a column are skipped.
+func (rset *Rset) ScanStruct(ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errF("ScanStruct needs a pointer to a struct, not %T", ptr)
+	}
+	fields, _, err := rset.structFields(rv.Elem().Type())
+	if err != nil {
+		return errE(err)
+	}
+	return rset.scanFields(rv.Elem(), fields)
+}
+
+// All appends the (remaining) rows to the slice of structs or struct pointers
+// pointed at by slicePtr, as ScanStruct.
+//
+// If no row has been fetched yet, the columns are defined with the
+// GoColumnTypes of the field types first, so the values are fetched as
+// the types of the fields, without conversion.
+func (rset *Rset) All(slicePtr interface{}) error {
+	sv := reflect.ValueOf(slicePtr)
+	if sv.Kind() != reflect.Ptr || sv.IsNil() || sv.Elem().Kind() != reflect.Slice {
+		return errF("All needs a pointer to a slice, not %T", slicePtr)
+	}
+	slice := sv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errF("All needs a pointer to a slice of structs, not %T", slicePtr)
+	}
+	if err := rset.checkIsOpen(); err != nil {
+		return errE(err)
+	}
+	fields, gcts, err := rset.structFields(structType)
+	if err != nil {
+		return errE(err)
+	}
+	if err = rset.redefine(gcts); err != nil {
+		return errE(err)
+	}
+	for rset.Next() {
+		ev := reflect.New(structType)
+		if err = rset.scanFields(ev.Elem(), fields); err != nil {
+			return err
+		}
+		if elemType.Kind() != reflect.Ptr {
+			ev = ev.Elem()
+		}
+		slice.Set(reflect.Append(slice, ev))
+	}
+	return rset.Err()
+}
+
+// structFields returns the index of the field of each column (-1 if none),
+// and the GoColumnType of the field, if the column can be fetched as it.
+func (rset *Rset) structFields(typ reflect.Type) (fields []int, gcts []GoColumnType, err error) {
+	tbl, err := structTbl(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+	rset.RLock()
+	defer rset.RUnlock()
+	fields = make([]int, len(rset.Columns))
+	gcts = make([]GoColumnType, len(rset.Columns))
+	for i, c := range rset.Columns {
+		fields[i], gcts[i] = -1, D
+		for _, col := range tbl.cols {
+			if strings.EqualFold(col.name, c.Name) {
+				fields[i], gcts[i] = col.fieldIdx, fieldGct(c, typ.Field(col.fieldIdx).Type)
+				break
+			}
+		}
+	}
+	return fields, gcts, nil
+}
+
+// scanFields copies the columns of the current row into the fields of v.
+func (rset *Rset) scanFields(v reflect.Value, fields []int) error {
+	rset.RLock()
+	row, cols := rset.Row, rset.Columns
+	rset.RUnlock()
+	for i, fi := range fields {
+		if fi < 0 || i >= len(row) {
+			continue
+		}
+		if err := convertAssign(v.Field(fi).Addr().Interface(), row[i]); err != nil {
+			return errF("column %d (%s): %v", i+1, cols[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// redefine defines the columns again with gcts, if no row has been fetched yet.
+func (rset *Rset) redefine(gcts []GoColumnType) error {
+	rset.Lock()
+	defer rset.Unlock()
+	if rset.fetched != 0 || rset.finished || atomic.LoadInt32(&rset.index) != -1 {
+		return nil
+	}
+	for n, def := range rset.defs {
+		if def == nil {
+			continue
+		}
+		if err := def.close(); err != nil {
+			return err
+		}
+		rset.defs[n] = nil
+	}
+	return rset.defineColumns(gcts)
+}
+
+// fieldGct returns the GoColumnType of the field type (time.Time, the Ora
+// types, OCINum and *Lob included), if the column can be fetched as it;
+// otherwise D.
+func fieldGct(c Column, rt reflect.Type) GoColumnType {
+	gct := D
+	for g, t := range gctTypes {
+		if t == rt {
+			gct = g
+			break
+		}
+	}
+	var err error
+	switch c.Type {
+	case TypeNumber, TypeInteger, TypeBinaryDouble, TypeBinaryFloat:
+		err = checkNumericColumn(gct, c.Name)
+	case TypeDate, TypeTimestamp, TypeTimestampTZ, TypeTimestampLTZ:
+		err = checkTimeColumn(gct)
+	case TypeVarchar2, TypeLong:
+		err = checkStringColumn(gct)
+	case TypeChar:
+		// for char(1 char) columns, Length is 4 (AL32UTF8 charset)
+		if c.Length == 1 || c.Length == 4 {
+			err = checkBoolOrStringColumn(gct)
+		} else {
+			err = checkStringColumn(gct)
+		}
+	case TypeClob:
+		if gct != L {
+			err = checkStringColumn(gct)
+		}
+	case TypeBlob:
+		if gct != L {
+			err = checkBinColumn(gct)
+		}
+	case TypeRaw, TypeLongRaw:
+		err = checkBinColumn(gct)
+	default:
+		return D
+	}
+	if err != nil {
+		return D
+	}
+	return gct
+}
+
+// convertAssign copies src into the value pointed at by dest,
+// converting it as database/sql does.
+func convertAssign(dest, src interface{}) error {
+	if d, ok := dest.(*interface{}); ok {
+		*d = src
+		return nil
+	}
+	dpv := reflect.ValueOf(dest)
+	if dpv.Kind() != reflect.Ptr || dpv.IsNil() {
+		return errF("destination not a non-nil pointer: %T", dest)
+	}
+	dv := dpv.Elem()
+	if src != nil {
+		if sv := reflect.ValueOf(src); sv.Type().AssignableTo(dv.Type()) {
+			dv.Set(sv)
+			return nil
+		}
+	}
+	src, err := scanValue(src)
+	if err != nil {
+		return err
+	}
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if isNull, value := oraFields(dv); isNull.IsValid() {
+		dv.Set(reflect.Zero(dv.Type()))
+		if src == nil {
+			isNull.SetBool(true)
+			return nil
+		}
+		return convertAssign(value.Addr().Interface(), src)
+	}
+	if n, ok := dest.(*num.OCINum); ok && src != nil {
+		return n.SetString(asString(reflect.ValueOf(src)))
+	}
+	if dv.Kind() == reflect.Ptr {
+		if src == nil {
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		dv.Set(reflect.New(dv.Type().Elem()))
+		return convertAssign(dv.Interface(), src)
+	}
+	if src == nil {
+		return errF("converting NULL to %s is unsupported", dv.Kind())
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dv.Type()) {
+		dv.Set(sv)
+		return nil
+	}
+
+	switch dv.Kind() {
+	case reflect.String:
+		switch x := src.(type) {
+		case []byte:
+			dv.SetString(string(x))
+			return nil
+		case time.Time:
+			dv.SetString(x.Format(time.RFC3339Nano))
+			return nil
+		}
+		switch sv.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			dv.SetString(asString(sv))
+			return nil
+		}
+	case reflect.Slice:
+		if dv.Type().Elem().Kind() == reflect.Uint8 {
+			switch x := src.(type) {
+			case []byte:
+				dv.SetBytes(append([]byte(nil), x...))
+				return nil
+			case string:
+				dv.SetBytes([]byte(x))
+				return nil
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := asString(sv)
+		i, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+		if err != nil {
+			return errF("converting %T (%q) to %s: %v", src, s, dv.Kind(), err)
+		}
+		dv.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := asString(sv)
+		u, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+		if err != nil {
+			return errF("converting %T (%q) to %s: %v", src, s, dv.Kind(), err)
+		}
+		dv.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		s := asString(sv)
+		f, err := strconv.ParseFloat(s, dv.Type().Bits())
+		if err != nil {
+			return errF("converting %T (%q) to %s: %v", src, s, dv.Kind(), err)
+		}
+		dv.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		s := asString(sv)
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errF("converting %T (%q) to %s: %v", src, s, dv.Kind(), err)
+		}
+		dv.SetBool(b)
+		return nil
+	}
+	return errF("unsupported Scan, storing %T into %T", src, dest)
+}
+
+// scanValue returns the plain value of src: nil for NULL, the Value of
+// the Ora types, and the driver.Value of driver.Valuers (OCINum, *Lob).
+func scanValue(src interface{}) (interface{}, error) {
+	switch x := src.(type) {
+	case nil:
+		return nil, nil
+	case *Lob:
+		if x == nil || x.Reader == nil {
+			return nil, nil
+		}
+		return x.Bytes()
+	case num.OCINum:
+		return x.String(), nil
+	case driver.Valuer:
+		return x.Value()
+	}
+	sv := reflect.ValueOf(src)
+	switch sv.Kind() {
+	case reflect.Ptr:
+		if sv.IsNil() {
+			return nil, nil
+		}
+	case reflect.Struct:
+		if isNull, value := oraFields(sv); isNull.IsValid() {
+			if isNull.Bool() {
+				return nil, nil
+			}
+			return scanValue(value.Interface())
+		}
+	}
+	return src, nil
+}
+
+// oraFields returns the IsNull and Value fields of the Ora types (Int64,
+// String, ...); the zero reflect.Values for other types.
+func oraFields(v reflect.Value) (isNull, value reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return isNull, value
+	}
+	isNull, value = v.FieldByName("IsNull"), v.FieldByName("Value")
+	if !isNull.IsValid() || isNull.Kind() != reflect.Bool || !value.IsValid() {
+		return reflect.Value{}, reflect.Value{}
+	}
+	return isNull, value
+}
+
+// asString returns the string representation of the value, as database/sql does.
+func asString(rv reflect.Value) string {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Slice:
+		if b, ok := rv.Interface().([]byte); ok {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", rv.Interface())
+}
@@ -0,0 +1,79 @@
+package ora
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertAssign(t *testing.T) {
+	now := time.Now()
+	var (
+		i   int
+		i8  int8
+		u   uint32
+		f   float64
+		s   string
+		b   bool
+		p   []byte
+		tm  time.Time
+		ip  *int64
+		ns  sql.NullString
+		ni  sql.NullInt64
+		o   Int64
+		any interface{}
+	)
+	for k, tc := range []struct {
+		dest, src, want interface{}
+	}{
+		{&i, int64(42), 42},
+		{&i, "42", 42},
+		{&i, Int64{Value: 42}, 42},
+		{&i8, float64(7), int8(7)},
+		{&u, OCINum{}, uint32(0)},
+		{&f, "1.5", 1.5},
+		{&f, Float32{Value: 0.5}, 0.5},
+		{&s, int64(3), "3"},
+		{&s, String{Value: "x"}, "x"},
+		{&s, []byte("y"), "y"},
+		{&b, "true", true},
+		{&b, Bool{Value: true}, true},
+		{&p, "z", []byte("z")},
+		{&p, Raw{Value: []byte("r")}, []byte("r")},
+		{&tm, now, now},
+		{&tm, Time{Value: now}, now},
+		{&ip, Int64{IsNull: true}, (*int64)(nil)},
+		{&ns, nil, sql.NullString{}},
+		{&ns, String{Value: "n"}, sql.NullString{String: "n", Valid: true}},
+		{&ni, Int64{IsNull: true}, sql.NullInt64{}},
+		{&ni, int64(9), sql.NullInt64{Int64: 9, Valid: true}},
+		{&o, Int64{Value: 1}, Int64{Value: 1}},
+		{&o, int32(2), Int64{Value: 2}},
+		{&o, nil, Int64{IsNull: true}},
+		{&o, String{IsNull: true}, Int64{IsNull: true}},
+		{&any, "a", "a"},
+	} {
+		if err := convertAssign(tc.dest, tc.src); err != nil {
+			t.Errorf("%d. %T <- %#v: %v", k, tc.dest, tc.src, err)
+			continue
+		}
+		if got := reflect.ValueOf(tc.dest).Elem().Interface(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%d. %T <- %#v: got %#v, wanted %#v", k, tc.dest, tc.src, got, tc.want)
+		}
+	}
+
+	for k, tc := range []struct {
+		dest, src interface{}
+	}{
+		{&i, nil},
+		{&i, Int64{IsNull: true}},
+		{&i8, int64(300)},
+		{&i, "x"},
+		{&tm, "2017-01-01"},
+	} {
+		if err := convertAssign(tc.dest, tc.src); err == nil {
+			t.Errorf("%d. %T <- %#v: wanted error", k, tc.dest, tc.src)
+		}
+	}
+}
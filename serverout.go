@@ -0,0 +1,116 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+const (
+	// getLinesSQL reads the DBMS_OUTPUT buffer. It is not followed by
+	// logging the server output, not to recurse.
+	getLinesSQL = "BEGIN DBMS_OUTPUT.GET_LINES(:1, :2); END;"
+	// getLinesBatch is the number of lines read by one GET_LINES call.
+	getLinesBatch = 128
+)
+
+// EnableServerOutput enables the DBMS_OUTPUT buffer of the session,
+// with bufSize bytes; zero or negative bufSize means unlimited.
+//
+// The lines written by DBMS_OUTPUT.PUT_LINE can be read with
+// ServerOutputLines or ReadServerOutput, or logged automatically after
+// each Stmt.Exe by setting LogStmtCfg.ServerOutput.
+func (ses *Ses) EnableServerOutput(bufSize int) error {
+	var err error
+	if bufSize <= 0 {
+		_, err = ses.PrepAndExe("BEGIN DBMS_OUTPUT.ENABLE(NULL); END;")
+	} else {
+		_, err = ses.PrepAndExe("BEGIN DBMS_OUTPUT.ENABLE(:1); END;", int64(bufSize))
+	}
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&ses.serverOutput, 1)
+	return nil
+}
+
+// DisableServerOutput disables the DBMS_OUTPUT buffer of the session.
+func (ses *Ses) DisableServerOutput() error {
+	atomic.StoreInt32(&ses.serverOutput, 0)
+	_, err := ses.PrepAndExe("BEGIN DBMS_OUTPUT.DISABLE; END;")
+	return err
+}
+
+// ServerOutputLines returns the lines in the DBMS_OUTPUT buffer of the
+// session, and removes them from the buffer.
+//
+// The lines longer than StmtCfg.StringPtrBufferSize are truncated.
+func (ses *Ses) ServerOutputLines() ([]string, error) {
+	var all []string
+	err := ses.readServerOutput(func(lines []string) error {
+		all = append(all, lines...)
+		return nil
+	})
+	return all, err
+}
+
+// ReadServerOutput writes the lines in the DBMS_OUTPUT buffer of the
+// session to w, each followed by a newline, and removes them from the buffer.
+func (ses *Ses) ReadServerOutput(w io.Writer) error {
+	return ses.readServerOutput(func(lines []string) error {
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readServerOutput calls DBMS_OUTPUT.GET_LINES till the buffer is empty,
+// and passes each batch to f.
+func (ses *Ses) readServerOutput(f func(lines []string) error) error {
+	stmt, err := ses.Prep(getLinesSQL)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	lines := make([]string, 0, getLinesBatch)
+	for {
+		n := int64(getLinesBatch)
+		lines = lines[:0]
+		if _, err = stmt.ExeP(&lines, &n); err != nil {
+			return err
+		}
+		if n > int64(len(lines)) {
+			n = int64(len(lines))
+		}
+		if n > 0 {
+			if err = f(lines[:n]); err != nil {
+				return err
+			}
+		}
+		if n < getLinesBatch {
+			return nil
+		}
+	}
+}
+
+// logServerOutput logs the DBMS_OUTPUT lines, if it is enabled for the session.
+func (ses *Ses) logServerOutput() {
+	if ses == nil || atomic.LoadInt32(&ses.serverOutput) == 0 {
+		return
+	}
+	Log := _drv.Cfg().Log
+	if err := ses.readServerOutput(func(lines []string) error {
+		for _, line := range lines {
+			Log.Logger.Infof("%v DBMS_OUTPUT: %s", ses.sysName(), line)
+		}
+		return nil
+	}); err != nil {
+		Log.Logger.Errorln(err)
+	}
+}
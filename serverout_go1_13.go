@@ -0,0 +1,43 @@
+// +build go1.13
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"database/sql"
+)
+
+// ConnSes calls f with the Ses of the database/sql connection,
+// to reach the native API (such as the DBMS_OUTPUT functions) from
+// database/sql.
+func ConnSes(conn *sql.Conn, f func(*Ses) error) error {
+	return conn.Raw(func(driverConn interface{}) error {
+		con, ok := driverConn.(*Con)
+		if !ok {
+			return errF("%T is not an *ora.Con", driverConn)
+		}
+		if err := con.checkIsOpen(); err != nil {
+			return err
+		}
+		return f(con.ses)
+	})
+}
+
+// EnableConnServerOutput enables the DBMS_OUTPUT buffer of the session of
+// the database/sql connection, as Ses.EnableServerOutput.
+func EnableConnServerOutput(conn *sql.Conn, bufSize int) error {
+	return ConnSes(conn, func(ses *Ses) error { return ses.EnableServerOutput(bufSize) })
+}
+
+// ConnServerOutputLines returns the lines in the DBMS_OUTPUT buffer of the
+// session of the database/sql connection, as Ses.ServerOutputLines.
+func ConnServerOutputLines(conn *sql.Conn) (lines []string, err error) {
+	err = ConnSes(conn, func(ses *Ses) error {
+		lines, err = ses.ServerOutputLines()
+		return err
+	})
+	return lines, err
+}
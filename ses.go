@@ -12,6 +12,7 @@ import "C"
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -66,6 +67,10 @@ func (c SesCfg) SetByteSlice(gct GoColumnType) SesCfg {
 	c.StmtCfg = c.StmtCfg.SetByteSlice(gct)
 	return c
 }
+func (c SesCfg) SetCallTimeout(d time.Duration) SesCfg {
+	c.StmtCfg = c.StmtCfg.SetCallTimeout(d)
+	return c
+}
 func (c SesCfg) SetNumberInt(gct GoColumnType) SesCfg {
 	c.StmtCfg = c.StmtCfg.SetNumberInt(gct)
 	return c
@@ -207,12 +212,18 @@ type Ses struct {
 	ocises    *C.OCISession
 	isLocked  bool
 
-	openStmts *stmtList
-	openTxs   *txList
+	openStmts     *stmtList
+	openTxs       *txList
+	openSubscrs   *subscrList
+	openGlobalTxs *globalTxList
 
 	insteadClose func(ses *Ses) error
 	timezone     *time.Location
 
+	openedAt     time.Time
+	bad          int32
+	serverOutput int32 // DBMS_OUTPUT is enabled
+
 	sysNamer
 }
 
@@ -298,8 +309,11 @@ func (ses *Ses) close() (err error) {
 		ses.srv = nil
 		ses.ocisvcctx = nil
 		ses.ocises = nil
+		ses.openedAt = time.Time{}
 		ses.openStmts.clear()
 		ses.openTxs.clear()
+		ses.openSubscrs.clear()
+		ses.openGlobalTxs.clear()
 		ses.Unlock()
 		_drv.sesPool.Put(ses)
 
@@ -317,10 +331,13 @@ func (ses *Ses) close() (err error) {
 	// Any open transactions will be timedout by the server
 	// if not explicitly committed or rolledback.
 	ses.RLock()
-	openTxs, openStmts := ses.openTxs, ses.openStmts
+	openTxs, openStmts, openSubscrs := ses.openTxs, ses.openStmts, ses.openSubscrs
+	openGlobalTxs := ses.openGlobalTxs
 	env, srv := ses.Env(), ses.srv
 	ocises, ocisvcctx := ses.ocises, ses.ocisvcctx
 	ses.RUnlock()
+	openSubscrs.closeAll(errs) // unregister subscriptions
+	openGlobalTxs.releaseAll() // free the trans handles of attached global transactions
 	openTxs.closeAll(errs)
 	openStmts.closeAll(errs) // close statements
 
@@ -370,18 +387,30 @@ func (ses *Ses) close() (err error) {
 // then Exe separately (and close the Stmt returned by Prep after finishing with
 // those objects).
 func (ses *Ses) PrepAndExe(sql string, params ...interface{}) (rowsAffected uint64, err error) {
-	return ses.prepAndExe(sql, false, params...)
+	return ses.prepAndExe(context.Background(), sql, false, params...)
 }
 
 // PrepAndExeP prepares and executes a SQL statement returning the number of rows
 // affected and a possible error, using ExeP, so passing arrays as is.
 func (ses *Ses) PrepAndExeP(sql string, params ...interface{}) (rowsAffected uint64, err error) {
-	return ses.prepAndExe(sql, true, params...)
+	return ses.prepAndExe(context.Background(), sql, true, params...)
+}
+
+// PrepAndExeContext is like PrepAndExe, but Breaks the execution when ctx is done,
+// returning ctx.Err().
+func (ses *Ses) PrepAndExeContext(ctx context.Context, sql string, params ...interface{}) (rowsAffected uint64, err error) {
+	return ses.prepAndExe(ctx, sql, false, params...)
+}
+
+// PrepAndExePContext is like PrepAndExeP, but Breaks the execution when ctx is done,
+// returning ctx.Err().
+func (ses *Ses) PrepAndExePContext(ctx context.Context, sql string, params ...interface{}) (rowsAffected uint64, err error) {
+	return ses.prepAndExe(ctx, sql, true, params...)
 }
 
 // prepAndExe prepares and executes a SQL statement returning the number of rows
 // affected and a possible error.
-func (ses *Ses) prepAndExe(sql string, isAssocArray bool, params ...interface{}) (rowsAffected uint64, err error) {
+func (ses *Ses) prepAndExe(ctx context.Context, sql string, isAssocArray bool, params ...interface{}) (rowsAffected uint64, err error) {
 	defer func() {
 		if value := recover(); value != nil {
 			err = errR(value)
@@ -405,9 +434,9 @@ func (ses *Ses) prepAndExe(sql string, isAssocArray bool, params ...interface{})
 		return 0, errE(err)
 	}
 	if isAssocArray {
-		rowsAffected, err = stmt.ExeP(params...)
+		rowsAffected, err = stmt.ExePContext(ctx, params...)
 	} else {
-		rowsAffected, err = stmt.Exe(params...)
+		rowsAffected, err = stmt.ExeContext(ctx, params...)
 	}
 	if err != nil {
 		return rowsAffected, errE(err)
@@ -423,6 +452,12 @@ func (ses *Ses) prepAndExe(sql string, isAssocArray bool, params ...interface{})
 // The *Stmt internal to this method is automatically closed when the *Rset
 // retrieves all rows or returns an error.
 func (ses *Ses) PrepAndQry(sql string, params ...interface{}) (rset *Rset, err error) {
+	return ses.PrepAndQryContext(context.Background(), sql, params...)
+}
+
+// PrepAndQryContext is like PrepAndQry, but Breaks the query when ctx is done,
+// returning ctx.Err().
+func (ses *Ses) PrepAndQryContext(ctx context.Context, sql string, params ...interface{}) (rset *Rset, err error) {
 	ses.log(_drv.Cfg().Log.Ses.PrepAndQry)
 	err = ses.checkClosed()
 	if err != nil {
@@ -433,7 +468,7 @@ func (ses *Ses) PrepAndQry(sql string, params ...interface{}) (rset *Rset, err e
 		defer stmt.Close()
 		return nil, errE(err)
 	}
-	rset, err = stmt.Qry(params...)
+	rset, err = stmt.QryContext(ctx, params...)
 	if err != nil {
 		defer stmt.Close()
 		return nil, errE(err)
@@ -719,6 +754,13 @@ type txOption struct {
 func TxFlags(flags uint32) TxOption            { return func(o *txOption) { o.flags = flags } }
 func TxTimeout(timeout time.Duration) TxOption { return func(o *txOption) { o.timeout = timeout } }
 
+// Transaction flags, to be used with TxFlags.
+const (
+	TxReadOnly     = uint32(C.OCI_TRANS_READONLY)
+	TxReadWrite    = uint32(C.OCI_TRANS_READWRITE)
+	TxSerializable = uint32(C.OCI_TRANS_SERIALIZABLE)
+)
+
 // StartTx starts an Oracle transaction returning a *Tx and possible error.
 func (ses *Ses) StartTx(opts ...TxOption) (tx *Tx, err error) {
 	ses.log(_drv.Cfg().Log.Ses.StartTx)
@@ -782,21 +824,46 @@ func (ses *Ses) Ping() (err error) {
 		env.ocierr,    //OCIError      *errhp,
 		C.OCI_DEFAULT) //ub4           mode );
 	ses.RUnlock()
+	_stats.roundTrip()
 	if r == C.OCI_ERROR {
-		return errE(env.ociError())
+		return errE(ses.markBad(env.ociError()))
 	}
 	return nil
 }
 
+// PingContext is like Ping, but Breaks the call when ctx is done,
+// returning ctx.Err().
+func (ses *Ses) PingContext(ctx context.Context) error {
+	return runCtx(ctx, ses, ses.Ping)
+}
+
+// StartTxContext is like StartTx, but Breaks the call when ctx is done,
+// returning ctx.Err().
+func (ses *Ses) StartTxContext(ctx context.Context, opts ...TxOption) (tx *Tx, err error) {
+	err = runCtx(ctx, ses, func() error {
+		var err error
+		tx, err = ses.StartTx(opts...)
+		return err
+	})
+	if err != nil && tx != nil {
+		tx.Rollback()
+		tx = nil
+	}
+	return tx, err
+}
+
 // Break stops the currently running OCI function.
+//
+// The running function holds the read lock of the session,
+// so Break must not acquire the write lock.
 func (ses *Ses) Break() (err error) {
 	ses.log(_drv.Cfg().Log.Ses.Break)
 	err = ses.checkClosed()
 	if err != nil {
 		return errE(err)
 	}
-	ses.Lock()
-	defer ses.Unlock()
+	ses.RLock()
+	defer ses.RUnlock()
 	env := ses.Env()
 	if r := C.OCIBreak(unsafe.Pointer(ses.ocisvcctx), env.ocierr); r == C.OCI_ERROR {
 		return errE(env.ociError())
@@ -823,6 +890,65 @@ func (ses *Ses) NumTx() int {
 	return openTxs.len()
 }
 
+// Endpoint returns the Dblink of the server the session is connected to.
+func (ses *Ses) Endpoint() string {
+	ses.RLock()
+	srv := ses.srv
+	ses.RUnlock()
+	if srv == nil {
+		return ""
+	}
+	if c := srv.cfg.Load(); c != nil {
+		return c.(SrvCfg).Dblink
+	}
+	return ""
+}
+
+// OpenedAt returns the time the session was opened.
+func (ses *Ses) OpenedAt() time.Time {
+	ses.RLock()
+	t := ses.openedAt
+	ses.RUnlock()
+	return t
+}
+
+// markBad records that the session's connection is lost, if err says so,
+// for the pools to drop the session instead of reusing it.
+//
+// Returns err unchanged.
+func (ses *Ses) markBad(err error) error {
+	if ses != nil && isBadConnErr(err) {
+		ses.setBad(badConn, true)
+	}
+	return err
+}
+
+// The reasons of a session being bad, as bits of Ses.bad.
+const (
+	badConn     = 1 // an error said that the connection is lost
+	badFailover = 2 // the server is down, or failing over
+)
+
+// setBad sets or clears the reason of the session being bad.
+func (ses *Ses) setBad(reason int32, bad bool) {
+	for {
+		old := atomic.LoadInt32(&ses.bad)
+		v := old &^ reason
+		if bad {
+			v = old | reason
+		}
+		if v == old || atomic.CompareAndSwapInt32(&ses.bad, old, v) {
+			return
+		}
+	}
+}
+
+// isBad returns whether an error has been seen on this session which
+// means that its connection is lost, or its server is down.
+func (ses *Ses) isBad() bool {
+	return ses == nil || atomic.LoadInt32(&ses.bad) != 0
+}
+
 // IsOpen returns true when a session is open; otherwise, false.
 //
 // Calling Close will cause Ses.IsOpen to return false. Once closed, a session
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -25,6 +26,12 @@ type SrvCfg struct {
 
 	Pool PoolCfg
 
+	// OnFailover is called in the phases of the Transparent Application Failover,
+	// if the connection is configured for TAF (FAILOVER_MODE in the connect string).
+	//
+	// Only for connections without Pool.
+	OnFailover FailoverFunc
+
 	// StmtCfg configures new Stmts.
 	StmtCfg
 }
@@ -142,6 +149,7 @@ func (srv *Srv) close() (err error) {
 		if value := recover(); value != nil {
 			errs.PushBack(errR(value))
 		}
+		failoverSrvs.remove(srv.id)
 		srv.SetCfg(SrvCfg{})
 		openSess.clear()
 		srv.Lock()
@@ -382,6 +390,8 @@ func (srv *Srv) OpenSes(cfg SesCfg) (ses *Ses, err error) {
 	ses.srv = srv
 	ses.ocisvcctx = (*C.OCISvcCtx)(ocisvcctx)
 	ses.ocises = (*C.OCISession)(ocises)
+	ses.openedAt = time.Now()
+	atomic.StoreInt32(&ses.bad, 0)
 	if ses.id == 0 {
 		ses.id = _drv.sesId.nextId()
 	}
@@ -0,0 +1,166 @@
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats contains the statistics of a Pool, SrvPool or SesPool.
+type PoolStats struct {
+	Idle  int // number of idle elements
+	InUse int // number of elements got and not yet put back
+
+	Opened  uint64 // number of elements opened by the pool
+	Closed  uint64 // number of elements closed by the pool (dead, expired, or overflowing)
+	Evicted uint64 // number of idle elements closed by the evictor
+
+	WaitCount    uint64        // number of Gets which had to wait for an idle element
+	WaitDuration time.Duration // total time spent waiting for idle elements
+	FailedGets   uint64        // number of Gets returning an error
+}
+
+// poolCounters holds the counters of a pool, maintained with atomic operations.
+type poolCounters struct {
+	opened, closed, failedGets uint64
+	inUse                      int64
+}
+
+func (c *poolCounters) get(err error) {
+	if err != nil {
+		atomic.AddUint64(&c.failedGets, 1)
+		return
+	}
+	atomic.AddInt64(&c.inUse, 1)
+}
+func (c *poolCounters) put() {
+	if atomic.AddInt64(&c.inUse, -1) < 0 {
+		atomic.AddInt64(&c.inUse, 1) // put back a foreign element
+	}
+}
+
+func (c *poolCounters) stats(idle ...*idlePool) PoolStats {
+	st := PoolStats{
+		InUse:      int(atomic.LoadInt64(&c.inUse)),
+		Opened:     atomic.LoadUint64(&c.opened),
+		Closed:     atomic.LoadUint64(&c.closed),
+		FailedGets: atomic.LoadUint64(&c.failedGets),
+	}
+	for i, p := range idle {
+		if i == 0 {
+			st.Idle = p.Len()
+		}
+		st.Closed += atomic.LoadUint64(&p.closed)
+		st.Evicted += atomic.LoadUint64(&p.evicted)
+		st.WaitCount += atomic.LoadUint64(&p.waitCount)
+		st.WaitDuration += time.Duration(atomic.LoadUint64(&p.waitNanos))
+	}
+	return st
+}
+
+// DrvStats contains the driver-wide statistics.
+type DrvStats struct {
+	Executions      uint64 // number of statement executions
+	Fetches         uint64 // number of fetch calls
+	RoundTrips      uint64 // number of OCI calls requiring a round trip to the server
+	RowsFetched     uint64 // number of rows fetched
+	LobBytesRead    uint64 // number of bytes read from LOBs
+	LobBytesWritten uint64 // number of bytes written to LOBs
+
+	Errors map[int]uint64 // number of errors, by ORA code
+}
+
+// drvStats holds the driver-wide counters, maintained with atomic operations.
+type drvStats struct {
+	executions, fetches, roundTrips, rowsFetched uint64
+	lobRead, lobWritten                          uint64
+
+	errorsMu sync.RWMutex
+	errors   map[int]*uint64
+}
+
+var _stats drvStats
+
+// publishMu serializes PublishExpvar.
+var publishMu sync.Mutex
+
+func (s *drvStats) execute() {
+	atomic.AddUint64(&s.executions, 1)
+	atomic.AddUint64(&s.roundTrips, 1)
+}
+func (s *drvStats) fetch(rows int64) {
+	atomic.AddUint64(&s.fetches, 1)
+	atomic.AddUint64(&s.roundTrips, 1)
+	if rows > 0 {
+		atomic.AddUint64(&s.rowsFetched, uint64(rows))
+	}
+}
+func (s *drvStats) roundTrip() { atomic.AddUint64(&s.roundTrips, 1) }
+func (s *drvStats) lobRW(read, written uint64) {
+	atomic.AddUint64(&s.roundTrips, 1)
+	if read != 0 {
+		atomic.AddUint64(&s.lobRead, read)
+	}
+	if written != 0 {
+		atomic.AddUint64(&s.lobWritten, written)
+	}
+}
+
+func (s *drvStats) error(code int) {
+	s.errorsMu.RLock()
+	n := s.errors[code]
+	s.errorsMu.RUnlock()
+	if n == nil {
+		s.errorsMu.Lock()
+		if n = s.errors[code]; n == nil {
+			if s.errors == nil {
+				s.errors = make(map[int]*uint64)
+			}
+			n = new(uint64)
+			s.errors[code] = n
+		}
+		s.errorsMu.Unlock()
+	}
+	atomic.AddUint64(n, 1)
+}
+
+// Stats returns a snapshot of the driver-wide statistics.
+func Stats() DrvStats {
+	s := &_stats
+	st := DrvStats{
+		Executions:      atomic.LoadUint64(&s.executions),
+		Fetches:         atomic.LoadUint64(&s.fetches),
+		RoundTrips:      atomic.LoadUint64(&s.roundTrips),
+		RowsFetched:     atomic.LoadUint64(&s.rowsFetched),
+		LobBytesRead:    atomic.LoadUint64(&s.lobRead),
+		LobBytesWritten: atomic.LoadUint64(&s.lobWritten),
+	}
+	s.errorsMu.RLock()
+	st.Errors = make(map[int]uint64, len(s.errors))
+	for code, n := range s.errors {
+		st.Errors[code] = atomic.LoadUint64(n)
+	}
+	s.errorsMu.RUnlock()
+	return st
+}
+
+// PublishExpvar publishes the driver-wide statistics with expvar, under the given name
+// (defaults to "ora").
+//
+// It does nothing if the name is already registered, so it can be called more than once.
+func PublishExpvar(name string) {
+	if name == "" {
+		name = "ora"
+	}
+	publishMu.Lock()
+	defer publishMu.Unlock()
+	if expvar.Get(name) != nil {
+		return
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} { return Stats() }))
+}
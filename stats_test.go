@@ -0,0 +1,96 @@
+package ora
+
+import (
+	"errors"
+	"expvar"
+	"testing"
+)
+
+type testCloser struct{ closed int }
+
+func (c *testCloser) Close() error { c.closed++; return nil }
+
+func TestPoolStats(t *testing.T) {
+	var c poolCounters
+	idle := newIdlePool(2)
+
+	c.get(nil)
+	c.get(nil)
+	c.get(errors.New("get"))
+	if st := c.stats(idle); st.InUse != 2 || st.FailedGets != 1 || st.Idle != 0 {
+		t.Errorf("after Get: got %+v", st)
+	}
+
+	if idle.Get() != nil || c.stats(idle).WaitCount != 0 {
+		t.Errorf("empty Get: got %+v", c.stats(idle))
+	}
+	if idle.GetWait() != nil {
+		t.Error("GetWait returned an element from an empty pool")
+	}
+	if st := c.stats(idle); st.WaitCount != 1 || st.WaitDuration < poolWaitGet {
+		t.Errorf("after GetWait: got %+v", st)
+	}
+
+	a, b := &testCloser{}, &testCloser{}
+	idle.Put(a)
+	c.put()
+	idle.Put(b)
+	c.put()
+	c.put() // a foreign element
+	if st := c.stats(idle); st.InUse != 0 || st.Idle != 2 {
+		t.Errorf("after Put: got %+v", st)
+	}
+
+	idle.Evict(0)
+	if st := c.stats(idle); st.Evicted != 2 || st.Idle != 0 {
+		t.Errorf("after Evict: got %+v", st)
+	}
+
+	d := &testCloser{}
+	idle.Put(d)
+	if err := idle.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a.closed+b.closed != 2 || d.closed != 1 {
+		t.Errorf("closed: got %d, %d, %d", a.closed, b.closed, d.closed)
+	}
+}
+
+func TestDrvStats(t *testing.T) {
+	before := Stats()
+	_stats.execute()
+	_stats.fetch(3)
+	_stats.lobRW(5, 7)
+	_stats.error(1)
+	after := Stats()
+	if d := after.Executions - before.Executions; d != 1 {
+		t.Errorf("executions: got %d, wanted 1", d)
+	}
+	if d := after.Fetches - before.Fetches; d != 1 {
+		t.Errorf("fetches: got %d, wanted 1", d)
+	}
+	if d := after.RowsFetched - before.RowsFetched; d != 3 {
+		t.Errorf("rows fetched: got %d, wanted 3", d)
+	}
+	if d := after.RoundTrips - before.RoundTrips; d != 3 {
+		t.Errorf("round trips: got %d, wanted 3", d)
+	}
+	if r, w := after.LobBytesRead-before.LobBytesRead, after.LobBytesWritten-before.LobBytesWritten; r != 5 || w != 7 {
+		t.Errorf("lob bytes: got %d/%d, wanted 5/7", r, w)
+	}
+	if d := after.Errors[1] - before.Errors[1]; d != 1 {
+		t.Errorf("ORA-00001: got %d, wanted 1", d)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	PublishExpvar("ora_test")
+	PublishExpvar("ora_test")
+	v := expvar.Get("ora_test")
+	if v == nil {
+		t.Fatal("ora_test is not published")
+	}
+	if s := v.String(); s == "" || s == "null" {
+		t.Errorf("got %q", s)
+	}
+}
@@ -44,6 +44,12 @@ type LogStmtCfg struct {
 	//
 	// The default is true.
 	Bind bool
+
+	// ServerOutput determines whether the DBMS_OUTPUT lines are read and
+	// logged after each Stmt.Exe, on the sessions with EnableServerOutput.
+	//
+	// The default is false.
+	ServerOutput bool
 }
 
 // NewLogStmtCfg creates a LogStmtCfg with default values.
@@ -75,6 +81,9 @@ type Stmt struct {
 	stringPtrBufferSize int
 	bindInfo
 
+	batchErrors bool          // execute arrays with OCI_BATCH_ERRORS
+	rowErrors   []RejectedRow // the row errors of the last batch execution
+
 	openRsets *rsetList
 
 	sysNamer
@@ -179,6 +188,8 @@ func (stmt *Stmt) close() (err error) {
 		stmt.bnds = nil
 		stmt.hasPtrBind = false
 		stmt.bindInfo = bindInfo{}
+		stmt.batchErrors = false
+		stmt.rowErrors = nil
 		stmt.openRsets.clear()
 		_drv.stmtPool.Put(stmt)
 		stmt.Unlock()
@@ -227,6 +238,38 @@ func (stmt *Stmt) ExeP(params ...interface{}) (rowsAffected uint64, err error) {
 	return rowsAffected, err
 }
 
+// ExeContext is like Exe, but Breaks the execution when ctx is done,
+// returning ctx.Err().
+func (stmt *Stmt) ExeContext(ctx context.Context, params ...interface{}) (rowsAffected uint64, err error) {
+	err = runCtx(ctx, stmt.getSes(), func() error {
+		var err error
+		rowsAffected, _, err = stmt.exeC(ctx, params, false)
+		return err
+	})
+	return rowsAffected, err
+}
+
+// ExePContext is like ExeP, but Breaks the execution when ctx is done,
+// returning ctx.Err().
+func (stmt *Stmt) ExePContext(ctx context.Context, params ...interface{}) (rowsAffected uint64, err error) {
+	err = runCtx(ctx, stmt.getSes(), func() error {
+		var err error
+		rowsAffected, _, err = stmt.exeC(ctx, params, true)
+		return err
+	})
+	return rowsAffected, err
+}
+
+// getSes returns the session of the statement.
+func (stmt *Stmt) getSes() *Ses {
+	if stmt == nil {
+		return nil
+	}
+	stmt.RLock()
+	defer stmt.RUnlock()
+	return stmt.ses
+}
+
 // Parse the statement, and return the syntax errors - WITHOUT executing it.
 // Rejects ALTER statements, as they're executed anyway by Oracle...
 func (stmt *Stmt) Parse() (err error) {
@@ -262,7 +305,7 @@ func (stmt *Stmt) Parse() (err error) {
 	stmt.ses.RUnlock()
 	stmt.RUnlock()
 	if r == C.OCI_ERROR {
-		return errE(env.ociError())
+		return errE(stmt.stmtErr(env.ociError()))
 	}
 	return nil
 }
@@ -327,10 +370,19 @@ func (stmt *Stmt) exeC(ctx context.Context, params []interface{}, isAssocArray b
 			autoCommit = true
 		}
 	}
+	stmt.Lock()
+	batchErrors := stmt.batchErrors
+	stmt.rowErrors = stmt.rowErrors[:0] // not to report the errors of the previous batch
+	stmt.Unlock()
+	if batchErrors {
+		mode |= C.OCI_BATCH_ERRORS
+	}
 	stmt.logF(_drv.Cfg().Log.Stmt.Exe, "iterations=%d autoCommit=%t", iterations, autoCommit)
 	// Execute statement on Oracle server
+	stopTimeout := stmt.getSes().startCallTimeout(stmt.Cfg().CallTimeout())
 	stmt.RLock()
 	env := stmt.Env()
+	ses := stmt.ses
 	stmt.ses.RLock()
 	r := C.OCIStmtExecute(
 		stmt.ses.ocisvcctx, //OCISvcCtx           *svchp,
@@ -345,8 +397,18 @@ func (stmt *Stmt) exeC(ctx context.Context, params []interface{}, isAssocArray b
 	stmtType, hasPtrBind := stmt.stmtType, stmt.hasPtrBind
 	stmt.RUnlock()
 	stmt.logF(_drv.Cfg().Log.Stmt.Exe, "returned %d, hasPtrBind=%t", r, hasPtrBind)
+	_stats.execute()
 	if r == C.OCI_ERROR {
-		return 0, 0, errE(env.ociError())
+		err = stmt.stmtErr(ses.markBad(env.ociError()))
+		// a batch of a single row may fail with its row error
+		if batchErrors && stmt.getRowErrors() == nil && stmt.hasRowErrors() {
+			err = nil
+		}
+	} else if batchErrors {
+		err = stmt.getRowErrors()
+	}
+	if err = stopTimeout(err); err != nil {
+		return 0, 0, errE(err)
 	}
 	// Get rowsAffected based on statement type
 	switch stmtType {
@@ -370,6 +432,9 @@ func (stmt *Stmt) exeC(ctx context.Context, params []interface{}, isAssocArray b
 			return rowsAffected, lastInsertId, errE(err)
 		}
 	}
+	if _drv.Cfg().Log.Stmt.ServerOutput && stmt.sql != getLinesSQL {
+		ses.logServerOutput()
+	}
 	return rowsAffected, lastInsertId, nil
 }
 
@@ -378,6 +443,21 @@ func (stmt *Stmt) Qry(params ...interface{}) (*Rset, error) {
 	return stmt.qry(params)
 }
 
+// QryContext is like Qry, but Breaks the query when ctx is done,
+// returning ctx.Err().
+func (stmt *Stmt) QryContext(ctx context.Context, params ...interface{}) (rset *Rset, err error) {
+	err = runCtx(ctx, stmt.getSes(), func() error {
+		var err error
+		rset, err = stmt.qryC(ctx, params)
+		return err
+	})
+	if err != nil && rset != nil {
+		rset.closeWithRemove()
+		rset = nil
+	}
+	return rset, err
+}
+
 // qry runs a SQL query on an Oracle server returning a *Rset and possible error.
 func (stmt *Stmt) qry(params []interface{}) (rset *Rset, err error) {
 	return stmt.qryC(context.Background(), params)
@@ -407,7 +487,19 @@ func (stmt *Stmt) qryC(ctx context.Context, params []interface{}) (rset *Rset, e
 	if err != nil {
 		return nil, errE(err)
 	}
+	snapshot := stmt.Cfg().Snapshot()
+	if s, ok := ctxSnapshot(ctx); ok {
+		snapshot = s
+	}
+	if !snapshot.IsZero() {
+		disableFlashback, err := stmt.getSes().enableFlashback(snapshot)
+		if err != nil {
+			return nil, errE(err)
+		}
+		defer disableFlashback()
+	}
 	// Query statement on Oracle server
+	stopTimeout := stmt.getSes().startCallTimeout(stmt.Cfg().CallTimeout())
 	stmt.RLock()
 	env := stmt.Env()
 	ses := stmt.ses
@@ -426,8 +518,12 @@ func (stmt *Stmt) qryC(ctx context.Context, params []interface{}) (rset *Rset, e
 	ses.RUnlock()
 	hasPtrBind := stmt.hasPtrBind
 	stmt.RUnlock()
+	_stats.execute()
 	if r == C.OCI_ERROR {
-		return nil, errE(env.ociError())
+		err = stmt.stmtErr(ses.markBad(env.ociError()))
+	}
+	if err = stopTimeout(err); err != nil {
+		return nil, errE(err)
 	}
 	if hasPtrBind { // set any bind pointers
 		err = stmt.setBindPtrs()
@@ -1612,6 +1708,35 @@ func (stmt *Stmt) setPrefetchSize() error {
 	return nil
 }
 
+// stmtErr records the statement text, the parse error offset and the bind
+// count in the Oracle error err, just returned by the execution of the statement.
+func (stmt *Stmt) stmtErr(err error) error {
+	oe, ok := findORAError(err)
+	if !ok {
+		return err
+	}
+	var (
+		parseOffset C.ub2
+		offsetKnown bool
+		ociBinds    C.ub4
+	)
+	stmt.RLock()
+	sql, bindCount := stmt.sql, len(stmt.bnds)
+	if stmt.ocistmt != nil {
+		// the errors are ignored, not to overwrite the error being reported
+		env := stmt.Env()
+		if C.OCIAttrGet(unsafe.Pointer(stmt.ocistmt), C.OCI_HTYPE_STMT,
+			unsafe.Pointer(&ociBinds), nil, C.OCI_ATTR_BIND_COUNT, env.ocierr) == C.OCI_SUCCESS {
+			bindCount = int(ociBinds)
+		}
+		offsetKnown = C.OCIAttrGet(unsafe.Pointer(stmt.ocistmt), C.OCI_HTYPE_STMT,
+			unsafe.Pointer(&parseOffset), nil, C.OCI_ATTR_PARSE_ERROR_OFFSET, env.ocierr) == C.OCI_SUCCESS
+	}
+	stmt.RUnlock()
+	oe.setStmtInfo(sql, int(parseOffset), offsetKnown, bindCount)
+	return err
+}
+
 // attr gets an attribute from the statement handle. No locking occurs.
 func (stmt *Stmt) attr(attrSize C.ub4, attrType C.ub4) (unsafe.Pointer, error) {
 	attrup := C.malloc(C.size_t(attrSize))
@@ -4,6 +4,8 @@
 
 package ora
 
+import "time"
+
 // StmtCfg affects various aspects of a SQL statement.
 //
 // Assign values to StmtCfg prior to calling Stmt.Exe
@@ -19,6 +21,8 @@ type StmtCfg struct {
 	lobBufferSize       int
 	stringPtrBufferSize int
 	byteSlice           GoColumnType
+	callTimeout         time.Duration
+	snapshot            Snapshot
 
 	// IsAutoCommitting determines whether DML statements are automatically
 	// committed.
@@ -258,6 +262,48 @@ func (c StmtCfg) ByteSlice() GoColumnType {
 	return c.byteSlice
 }
 
+// SetCallTimeout sets the maximum time a single round-trip to the server
+// (execute or fetch) may take. Zero means no limit.
+//
+// With 18c+ clients this sets OCI_ATTR_CALL_TIMEOUT, with older clients a
+// watchdog Breaks the session when the time passes.
+// Either way, the call returns a *CallTimeoutError.
+func (c StmtCfg) SetCallTimeout(d time.Duration) StmtCfg {
+	if d < 0 {
+		if c.Err == nil {
+			c.Err = errNew("SetCallTimeout parameter 'd' must not be negative")
+		}
+		return c
+	}
+	c.callTimeout = d
+	return c
+}
+
+// CallTimeout returns the maximum time of a round-trip to the server.
+//
+// The default is 0, which means no limit.
+func (c StmtCfg) CallTimeout() time.Duration {
+	return c.callTimeout
+}
+
+// SetSnapshot makes the queries read the data as of the Snapshot, with
+// DBMS_FLASHBACK (which needs EXECUTE privilege on it).
+// The zero Snapshot reads the current data.
+//
+// Flashback can't be enabled in a transaction, so such queries must not be
+// run in one.
+func (c StmtCfg) SetSnapshot(s Snapshot) StmtCfg {
+	c.snapshot = s
+	return c
+}
+
+// Snapshot returns the Snapshot the queries read the data as of.
+//
+// The default is the zero Snapshot, which means the current data.
+func (c StmtCfg) Snapshot() Snapshot {
+	return c.snapshot
+}
+
 func (c StmtCfg) SetNumberInt(gct GoColumnType) StmtCfg {
 	c.RsetCfg = c.RsetCfg.SetNumberInt(gct)
 	return c
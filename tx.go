@@ -10,8 +10,10 @@ package ora
 */
 import "C"
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // LogTxCfg represents Tx logging configuration values.
@@ -25,6 +27,16 @@ type LogTxCfg struct {
 	//
 	// The default is true.
 	Rollback bool
+
+	// Savepoint determines whether the Tx.Savepoint method is logged.
+	//
+	// The default is true.
+	Savepoint bool
+
+	// RollbackTo determines whether the Tx.RollbackTo method is logged.
+	//
+	// The default is true.
+	RollbackTo bool
 }
 
 // NewLogTxCfg creates a LogTxCfg with default values.
@@ -32,6 +44,8 @@ func NewLogTxCfg() LogTxCfg {
 	c := LogTxCfg{}
 	c.Commit = true
 	c.Rollback = true
+	c.Savepoint = true
+	c.RollbackTo = true
 	return c
 }
 
@@ -41,9 +55,10 @@ func NewLogTxCfg() LogTxCfg {
 type Tx struct {
 	sync.RWMutex
 
-	cmu sync.Mutex
-	id  uint64
-	ses *Ses
+	cmu   sync.Mutex
+	id    uint64
+	ses   *Ses
+	spSeq uint64 // sequence of the savepoints generated by Nested
 }
 
 // checkIsOpen validates that the session is open.
@@ -104,8 +119,9 @@ func (tx *Tx) Commit() (err error) {
 		tx.ses.srv.env.ocierr, //OCIError     *errhp,
 		C.OCI_DEFAULT)         //ub4          flags );
 	tx.RUnlock()
+	_stats.roundTrip()
 	if r == C.OCI_ERROR {
-		return tx.ses.srv.env.ociError()
+		return tx.ses.markBad(tx.ses.srv.env.ociError())
 	}
 	return nil
 }
@@ -134,12 +150,107 @@ func (tx *Tx) Rollback() (err error) {
 		tx.ses.srv.env.ocierr, //OCIError     *errhp,
 		C.OCI_DEFAULT)         //ub4          flags );
 	tx.RUnlock()
+	_stats.roundTrip()
 	if r == C.OCI_ERROR {
-		return tx.ses.srv.env.ociError()
+		return tx.ses.markBad(tx.ses.srv.env.ociError())
+	}
+	return nil
+}
+
+// Savepoint creates a savepoint with the given name in the transaction.
+//
+// An existing savepoint with the same name is overwritten.
+func (tx *Tx) Savepoint(name string) error {
+	tx.log(_drv.Cfg().Log.Tx.Savepoint, name)
+	return tx.exeSavepoint("SAVEPOINT ", name)
+}
+
+// RollbackTo rolls back the work done after the savepoint with the given name,
+// keeping the transaction (and the savepoint) open.
+func (tx *Tx) RollbackTo(name string) error {
+	tx.log(_drv.Cfg().Log.Tx.RollbackTo, name)
+	return tx.exeSavepoint("ROLLBACK TO SAVEPOINT ", name)
+}
+
+// Nested runs f in a nested transaction, using a savepoint: if f returns an
+// error or panics, the work done by f is rolled back to the savepoint, and
+// the rest of the transaction is kept.
+//
+// Returns the error of f.
+func (tx *Tx) Nested(f func(*Tx) error) (err error) {
+	name := fmt.Sprintf("ORA_NESTED_%d", atomic.AddUint64(&tx.spSeq, 1))
+	if err = tx.Savepoint(name); err != nil {
+		return err
+	}
+	defer func() {
+		if value := recover(); value != nil {
+			tx.RollbackTo(name)
+			panic(value)
+		}
+		if err != nil {
+			if rbErr := tx.RollbackTo(name); rbErr != nil {
+				err = errF("%v (and rollback to %s: %v)", err, name, rbErr)
+			}
+		}
+	}()
+	return f(tx)
+}
+
+// exeSavepoint executes the savepoint command with the checked name.
+func (tx *Tx) exeSavepoint(command, name string) error {
+	if err := checkSavepointName(name); err != nil {
+		return err
+	}
+	if err := tx.checkIsOpen(); err != nil {
+		return err
+	}
+	_, err := tx.getSes().PrepAndExe(command + name)
+	return err
+}
+
+// checkSavepointName checks that name is a valid, unquoted Oracle identifier.
+func checkSavepointName(name string) error {
+	if name == "" || len(name) > 128 {
+		return errF("invalid savepoint name %q", name)
+	}
+	for i, r := range name {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case i > 0 && ('0' <= r && r <= '9' || r == '_' || r == '$' || r == '#'):
+		default:
+			return errF("invalid savepoint name %q", name)
+		}
 	}
 	return nil
 }
 
+// CommitContext is like Commit, but Breaks the call when ctx is done,
+// returning ctx.Err().
+func (tx *Tx) CommitContext(ctx context.Context) error {
+	return runCtx(ctx, tx.getSes(), tx.Commit)
+}
+
+// RollbackContext is like Rollback, but Breaks the call when ctx is done,
+// returning ctx.Err().
+func (tx *Tx) RollbackContext(ctx context.Context) error {
+	return runCtx(ctx, tx.getSes(), tx.Rollback)
+}
+
+// Ses returns the session of the transaction, to run statements in it.
+func (tx *Tx) Ses() *Ses {
+	return tx.getSes()
+}
+
+// getSes returns the session of the transaction.
+func (tx *Tx) getSes() *Ses {
+	if tx == nil {
+		return nil
+	}
+	tx.RLock()
+	defer tx.RUnlock()
+	return tx.ses
+}
+
 // sysName returns a string representing the Tx.
 func (tx *Tx) sysName() string {
 	if tx == nil {
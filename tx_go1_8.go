@@ -0,0 +1,61 @@
+// +build go1.8
+
+// Copyright 2017 Rana Ian, Tamás Gulácsi. All rights reserved.
+// Use of this source code is governed by The MIT License
+// found in the accompanying LICENSE file.
+
+package ora
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// Savepoint creates a savepoint with the given name in the database/sql
+// transaction, the same way as Tx.Savepoint.
+func Savepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	if err := checkSavepointName(name); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+// RollbackTo rolls back the database/sql transaction to the savepoint with
+// the given name, the same way as Tx.RollbackTo.
+func RollbackTo(ctx context.Context, tx *sql.Tx, name string) error {
+	if err := checkSavepointName(name); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+var nestedSeq uint64
+
+// Nested runs f in a nested transaction of the database/sql transaction,
+// the same way as Tx.Nested: if f returns an error or panics, the work done
+// by f is rolled back.
+//
+// Returns the error of f.
+func Nested(ctx context.Context, tx *sql.Tx, f func(*sql.Tx) error) (err error) {
+	name := fmt.Sprintf("ORA_NESTED_%d", atomic.AddUint64(&nestedSeq, 1))
+	if err = Savepoint(ctx, tx, name); err != nil {
+		return err
+	}
+	defer func() {
+		if value := recover(); value != nil {
+			RollbackTo(context.Background(), tx, name)
+			panic(value)
+		}
+		if err != nil {
+			// the rollback must be done even if ctx is already done.
+			if rbErr := RollbackTo(context.Background(), tx, name); rbErr != nil {
+				err = errF("%v (and rollback to %s: %v)", err, name, rbErr)
+			}
+		}
+	}()
+	return f(tx)
+}
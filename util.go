@@ -1,8 +1,6 @@
 package ora
 
 import (
-	"bytes"
-	"database/sql"
 	"errors"
 	"fmt"
 	"runtime"
@@ -163,10 +161,15 @@ func er(v ...interface{}) error {
 	if len(v) == 1 {
 		err, _ = v[0].(error)
 	}
-	if err == nil {
-		err = errors.New(fmt.Sprint(v...))
+	if oe, ok := err.(*ORAError); ok && oe.caller == nil {
+		// a fresh error from Env.ociError
+		oe.caller = errInfo(1)
+	} else {
+		if err == nil {
+			err = errors.New(fmt.Sprint(v...))
+		}
+		err = &ORAError{caller: errInfo(1), err: err}
 	}
-	err = &oraErr{Caller: errInfo(1), Underlying: err}
 	_drv.Cfg().Log.Logger.Errorln(err)
 	return err
 }
@@ -174,7 +177,7 @@ func er(v ...interface{}) error {
 // errF creates a formatted error with caller info.
 func errF(format string, v ...interface{}) error {
 	//err := errors.New(fmt.Sprintf("%v %v", errInfo(1), fmt.Sprintf(format, v...)))
-	err := &oraErr{Caller: errInfo(1), Underlying: fmt.Errorf(format, v...)}
+	err := &ORAError{caller: errInfo(1), err: fmt.Errorf(format, v...)}
 	_drv.Cfg().Log.Logger.Errorln(err)
 	return err
 }
@@ -192,10 +195,10 @@ func getStack() string {
 // errR creates a recovered error with caller info.
 func errR(v ...interface{}) error {
 	//err := errors.New(fmt.Sprintf("%v recovered: %v\n%s", errInfo(1), fmt.Sprint(v...), trace[:n]))
-	err := &oraErr{
-		Caller:     errInfo(1),
-		Underlying: errors.New(fmt.Sprint(v...)),
-		Trace:      getStack(),
+	err := &ORAError{
+		caller: errInfo(1),
+		err:    errors.New(fmt.Sprint(v...)),
+		trace:  getStack(),
 	}
 	_drv.Cfg().Log.Logger.Errorln(err)
 	return err
@@ -204,128 +207,11 @@ func errR(v ...interface{}) error {
 // errE wraps an error with caller info.
 func errE(e error) error {
 	//err := errors.New(fmt.Sprintf("%v %v", errInfo(1), e.Error()))
-	err := &oraErr{Caller: errInfo(1), Underlying: e}
+	err := &ORAError{caller: errInfo(1), err: e}
 	_drv.Cfg().Log.Logger.Errorln(err)
 	return err
 }
 
-type oraErr struct {
-	Caller     fmt.Stringer
-	Underlying error
-	Trace      string
-}
-
-func (e *oraErr) Error() string {
-	if e == nil {
-		return ""
-	}
-	if e.Caller != nil {
-		if len(e.Trace) > 0 {
-			return fmt.Sprintf("%v recovered: %v\n%s", e.Caller, e.Underlying, e.Trace)
-		}
-		return fmt.Sprintf("%v %v", e.Caller, e.Underlying)
-	}
-	return e.Underlying.Error()
-}
-
-func (e oraErr) Code() int {
-	if e.Underlying == nil {
-		return 0
-	}
-	if coder, ok := e.Underlying.(interface {
-		Code() int
-	}); ok {
-		return coder.Code()
-	}
-	errS := e.Error()
-	i := strings.Index(errS, "ORA-")
-	if i < 0 {
-		return 0
-	}
-	var code int
-	fmt.Scanf(errS[i+4:], "%d", &code)
-	return code
-}
-
-// DescribedColumn type for describing a column (see DescribeQuery).
-type DescribedColumn struct {
-	Column
-
-	Schema                 string
-	Nullable               bool
-	CharsetID, CharsetForm int
-}
-
-// DescribeQuery parses the query and returns the column types, as
-// DBMS_SQL.describe_column does.
-func DescribeQuery(db *sql.DB, qry string) ([]DescribedColumn, error) {
-	res := bytesPool.Get(32766)
-	defer bytesPool.Put(res)
-	for i := range res {
-		res[i] = 0
-	}
-	res = make([]byte, 32766)
-	if _, err := db.Exec(`DECLARE
-  c INTEGER;
-  col_cnt INTEGER;
-  rec_tab DBMS_SQL.DESC_TAB;
-  a DBMS_SQL.DESC_REC;
-  v_idx PLS_INTEGER;
-  res VARCHAR2(32767);
-BEGIN
-  c := DBMS_SQL.OPEN_CURSOR;
-  BEGIN
-    DBMS_SQL.PARSE(c, :1, DBMS_SQL.NATIVE);
-    DBMS_SQL.DESCRIBE_COLUMNS(c, col_cnt, rec_tab);
-    v_idx := rec_tab.FIRST;
-    WHILE v_idx IS NOT NULL LOOP
-      a := rec_tab(v_idx);
-      res := res||a.col_schema_name||' '||a.col_name||' '||a.col_type||' '||
-                  a.col_max_len||' '||a.col_precision||' '||a.col_scale||' '||
-                  (CASE WHEN a.col_null_ok THEN 1 ELSE 0 END)||' '||
-                  a.col_charsetid||' '||a.col_charsetform||
-                  CHR(10);
-      v_idx := rec_tab.NEXT(v_idx);
-    END LOOP;
-  EXCEPTION WHEN OTHERS THEN NULL;
-    DBMS_SQL.CLOSE_CURSOR(c);
-	RAISE;
-  END;
-  :2 := UTL_RAW.CAST_TO_RAW(res);
-END;`, qry, &res,
-	); err != nil {
-		return nil, err
-	}
-	if i := bytes.IndexByte(res, 0); i >= 0 {
-		res = res[:i]
-	}
-	lines := bytes.Split(res, []byte{'\n'})
-	cols := make([]DescribedColumn, 0, len(lines))
-	var nullable int
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-		var col DescribedColumn
-		switch j := bytes.IndexByte(line, ' '); j {
-		case -1:
-			continue
-		case 0:
-			line = line[1:]
-		default:
-			col.Schema, line = string(line[:j]), line[j+1:]
-		}
-		if n, err := fmt.Sscanf(string(line), "%s %d %d %d %d %d %d %d",
-			&col.Name, &col.Type, &col.Length, &col.Precision, &col.Scale, &nullable, &col.CharsetID, &col.CharsetForm,
-		); err != nil {
-			return cols, fmt.Errorf("parsing %q (parsed: %d): %v", line, n, err)
-		}
-		col.Nullable = nullable != 0
-		cols = append(cols, col)
-	}
-	return cols, nil
-}
-
 // CompileError represents a compile-time error as in user_errors view.
 type CompileError struct {
 	Owner, Name, Type    string
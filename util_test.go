@@ -21,3 +21,17 @@ func TestBoundingPower(t *testing.T) {
 		}
 	}
 }
+
+func TestSesSetBad(t *testing.T) {
+	var ses Ses
+	ses.setBad(badConn, true)
+	ses.setBad(badFailover, true)
+	ses.setBad(badFailover, false)
+	if !ses.isBad() {
+		t.Error("failover end cleared the lost connection")
+	}
+	ses.setBad(badConn, false)
+	if ses.isBad() {
+		t.Error("still bad")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -34,6 +35,45 @@ func TestNamedArgs(t *testing.T) {
 	t.Log(s)
 }
 
+func TestQueryContextCancelFetch(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The first rows come fast, then the fetch blocks on the huge COUNT(*).
+	rows, err := testDb.QueryContext(ctx,
+		`SELECT LEVEL FROM DUAL CONNECT BY LEVEL <= 1000
+		 UNION ALL
+		 SELECT COUNT(*) FROM all_objects A, all_objects B, all_objects C`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	time.AfterFunc(time.Second, cancel)
+	start := time.Now()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("fetching %d rows with cancel after 1s took %s", n, d)
+	}
+	if n > 1000 {
+		t.Errorf("the blocking fetch returned a row (%d)", n)
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	rows.Close()
+
+	// The sessions must be usable after the cancel.
+	for i := 0; i < 4; i++ {
+		var one int
+		if err := testDb.QueryRow("SELECT 1 FROM DUAL").Scan(&one); err != nil || one != 1 {
+			t.Errorf("%d. after cancel: got %d, %v", i, one, err)
+		}
+	}
+}
+
 func TestRapidCancelIssue192(t *testing.T) {
 	wait := uint64(500)
 	dbQuery := func(db *sql.DB) error {
@@ -77,3 +117,48 @@ func TestRapidCancelIssue192(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestColumnTypeScanType(t *testing.T) {
+	t.Parallel()
+	tbl := tableName()
+	if _, err := testDb.Exec("CREATE TABLE " + tbl + " (id NUMBER(9) NOT NULL, n NUMBER(9), f NUMBER(9,2), s VARCHAR2(10), d DATE)"); err != nil {
+		t.Fatal(err)
+	}
+	defer testDb.Exec("DROP TABLE " + tbl)
+	if _, err := testDb.Exec("INSERT INTO " + tbl + " (id) VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := testDb.Query("SELECT id, n, f, s, d FROM " + tbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]interface{}, len(cts))
+	for i, ct := range cts {
+		nullable, ok := ct.Nullable()
+		if !ok || nullable != (i != 0) {
+			t.Errorf("%s: got nullable=%t ok=%t", ct.Name(), nullable, ok)
+		}
+		dest[i] = reflect.New(ct.ScanType()).Interface()
+	}
+	if _, ok := dest[1].(*sql.NullInt64); !ok {
+		t.Errorf("got %T, wanted *sql.NullInt64", dest[1])
+	}
+	if _, ok := dest[3].(*sql.NullString); !ok {
+		t.Errorf("got %T, wanted *sql.NullString", dest[3])
+	}
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	if err := rows.Scan(dest...); err != nil {
+		t.Fatal(err)
+	}
+	if id := *dest[0].(*int64); id != 1 {
+		t.Errorf("got id %d, wanted 1", id)
+	}
+}
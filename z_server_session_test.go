@@ -151,3 +151,76 @@ func TestPool(t *testing.T) {
 	pool.Close()
 	T("Pool close", p2, s2)
 }
+
+func TestPoolMaxLifetime(t *testing.T) {
+	t.Parallel()
+	env, err := ora.OpenEnv()
+	testErr(err, t)
+	defer env.Close()
+	pool := env.NewPool(testSrvCfg, testSesCfg, 2,
+		ora.PoolMinIdle(1),
+		ora.PoolMaxLifetime(100*time.Millisecond),
+		ora.PoolValidateIdle(10*time.Millisecond),
+	)
+	defer pool.Close()
+
+	ses, err := pool.Get()
+	testErr(err, t)
+	opened := ses.OpenedAt()
+	pool.Put(ses)
+
+	time.Sleep(200 * time.Millisecond)
+	if ses, err = pool.Get(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Put(ses)
+	if !ses.OpenedAt().After(opened) {
+		t.Errorf("got session opened at %s, wanted a new one after %s", ses.OpenedAt(), opened)
+	}
+	if err = ses.Ping(); err != nil {
+		t.Error(err)
+	}
+	if st := pool.Stats(); st.Opened < 2 || st.Closed < 1 || st.InUse != 1 {
+		t.Errorf("got %+v, wanted at least 2 opened, 1 closed and 1 in use", st)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+	env, err := ora.OpenEnv()
+	testErr(err, t)
+	defer env.Close()
+	primary := env.NewPool(testSrvCfg, testSesCfg, 1)
+	replica := env.NewPool(testSrvCfg, testSesCfg, 1)
+	router := ora.NewRouter(primary, replica)
+	defer router.Close()
+
+	for i, tc := range []struct {
+		qry       string
+		readOnly  bool
+		onReplica bool
+	}{
+		{qry: "SELECT 1 FROM DUAL", onReplica: true},
+		{qry: "BEGIN NULL; END;"},
+		{qry: "UPDATE dual SET dummy = dummy WHERE 1 = 0"},
+	} {
+		ses, err := router.GetFor(tc.qry)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		p, r := primary.Stats().InUse, replica.Stats().InUse
+		ses.Close()
+		if tc.onReplica != (r == 1) || tc.onReplica == (p == 1) {
+			t.Errorf("%d. %q: got primary=%d replica=%d in use.", i, tc.qry, p, r)
+		}
+	}
+
+	ses, err := router.GetForTx(ora.TxFlags(ora.TxReadOnly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := replica.Stats().InUse; r != 1 {
+		t.Errorf("read-only tx: got replica=%d in use.", r)
+	}
+	ses.Close()
+}
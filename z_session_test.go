@@ -5,11 +5,15 @@
 package ora_test
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/rana/ora.v4"
 )
@@ -197,6 +201,552 @@ func TestSession_Tx_StartRollback(t *testing.T) {
 	}
 }
 
+func TestSession_Tx_Savepoint(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName, err := createTable(1, numberP38S0, ses)
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	tx, err := ses.StartTx()
+	testErr(err, t)
+
+	insert := fmt.Sprintf("insert into %v (c1) values (:1)", tableName)
+	_, err = ses.PrepAndExe(insert, int64(1))
+	testErr(err, t)
+	testErr(tx.Savepoint("sp1"), t)
+	_, err = ses.PrepAndExe(insert, int64(2))
+	testErr(err, t)
+	testErr(tx.RollbackTo("sp1"), t)
+
+	errNested := fmt.Errorf("nested")
+	if err = tx.Nested(func(tx *ora.Tx) error {
+		if _, err := ses.PrepAndExe(insert, int64(3)); err != nil {
+			return err
+		}
+		return errNested
+	}); err != errNested {
+		t.Errorf("expected %v, got %v", errNested, err)
+	}
+	testErr(tx.Nested(func(tx *ora.Tx) error {
+		_, err := ses.PrepAndExe(insert, int64(4))
+		return err
+	}), t)
+	testErr(tx.Commit(), t)
+
+	if err = tx.Savepoint("x'; DROP TABLE y"); err == nil {
+		t.Errorf("invalid savepoint name accepted")
+	}
+
+	rset, err := ses.PrepAndQry(fmt.Sprintf("select c1 from %v order by c1", tableName))
+	testErr(err, t)
+	var got []interface{}
+	for rset.Next() {
+		got = append(got, rset.Row[0])
+	}
+	testErr(rset.Err(), t)
+	if s := fmt.Sprint(got); s != "[1 4]" {
+		t.Errorf("expected [1 4], got %v", s)
+	}
+}
+
+func TestSession_GlobalTx(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName, err := createTable(1, numberP38S0, ses)
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	xid := ora.XID{
+		FormatID:            0x4f5241,
+		GlobalTransactionID: []byte(fmt.Sprintf("gtrid-%d", time.Now().UnixNano())),
+		BranchQualifier:     []byte("bqual-1"),
+	}
+	gtx, err := ses.StartGlobalTx(xid)
+	if err != nil {
+		t.Skipf("StartGlobalTx(%s): %v", xid, err)
+	}
+	_, err = ses.PrepAndExe(fmt.Sprintf("insert into %v (c1) values (:1)", tableName), int64(1))
+	testErr(err, t)
+	testErr(gtx.Detach(), t)
+
+	if gtx, err = ses.ResumeGlobalTx(xid); err != nil {
+		t.Fatal(err)
+	}
+	readOnly, err := gtx.Prepare()
+	testErr(err, t)
+	if readOnly {
+		t.Fatal("branch with insert prepared as read-only")
+	}
+	testErr(gtx.Commit(), t)
+
+	rset, err := ses.PrepAndQry(fmt.Sprintf("select c1 from %v", tableName))
+	testErr(err, t)
+	for rset.Next() {
+	}
+	testErr(rset.Err(), t)
+	if rset.Len() != 1 {
+		t.Errorf("row count: expected(%v), actual(%v)", 1, rset.Len())
+	}
+}
+
+func TestSession_ServerOutput(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	testErr(ses.EnableServerOutput(0), t)
+	defer ses.DisableServerOutput()
+	_, err = ses.PrepAndExe(`BEGIN
+  FOR i IN 1..300 LOOP
+    DBMS_OUTPUT.PUT_LINE('line '||i);
+  END LOOP;
+END;`)
+	testErr(err, t)
+	lines, err := ses.ServerOutputLines()
+	testErr(err, t)
+	if len(lines) != 300 || lines[0] != "line 1" || lines[299] != "line 300" {
+		t.Errorf("got %d lines (%q...)", len(lines), lines)
+	}
+
+	_, err = ses.PrepAndExe("BEGIN DBMS_OUTPUT.PUT_LINE('a'); DBMS_OUTPUT.PUT_LINE('b'); END;")
+	testErr(err, t)
+	var buf strings.Builder
+	testErr(ses.ReadServerOutput(&buf), t)
+	if got := buf.String(); got != "a\nb\n" {
+		t.Errorf("got %q, wanted %q", got, "a\nb\n")
+	}
+}
+
+func TestSession_Queue(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	qName := tableName()
+	if _, err = ses.PrepAndExe(`BEGIN
+  DBMS_AQADM.CREATE_QUEUE_TABLE(queue_table => :1, queue_payload_type => 'RAW');
+  DBMS_AQADM.CREATE_QUEUE(queue_name => :2, queue_table => :3);
+  DBMS_AQADM.START_QUEUE(queue_name => :4);
+END;`, qName+"_t", qName, qName+"_t", qName); err != nil {
+		t.Skipf("create queue: %v", err)
+	}
+	defer ses.PrepAndExe(`BEGIN
+  DBMS_AQADM.STOP_QUEUE(queue_name => :1);
+  DBMS_AQADM.DROP_QUEUE(queue_name => :2);
+  DBMS_AQADM.DROP_QUEUE_TABLE(queue_table => :3);
+END;`, qName, qName, qName+"_t")
+
+	q, err := ses.Queue(qName, ora.PayloadRAW)
+	testErr(err, t)
+	q.EnqOptions.Visibility = ora.VisibleImmediate
+	q.DeqOptions.Visibility = ora.VisibleImmediate
+	q.DeqOptions.Wait = ora.NoWait
+	msgs := []*ora.Message{
+		{Payload: []byte("first"), Correlation: "a"},
+		{Payload: []byte{0, 1, 2, 255}, Correlation: "b"},
+		{Payload: []byte("third"), Correlation: "a"},
+	}
+	testErr(q.Enqueue(msgs...), t)
+	for i, m := range msgs {
+		if len(m.MsgID) != 16 {
+			t.Errorf("%d. got MsgID %x", i, m.MsgID)
+		}
+	}
+
+	q.DeqOptions.Correlation = "b"
+	got, err := q.Dequeue(context.Background(), 10)
+	testErr(err, t)
+	if len(got) != 1 || !bytes.Equal(got[0].Payload, msgs[1].Payload) {
+		t.Fatalf("got %d messages, wanted %q", len(got), msgs[1].Payload)
+	}
+
+	q.DeqOptions.Correlation = ""
+	if got, err = q.Dequeue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got[0].Payload) != "first" || string(got[1].Payload) != "third" {
+		t.Errorf("got %d messages, wanted first and third", len(got))
+	}
+
+	q.DeqOptions.Wait = ora.WaitForever
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err = q.Dequeue(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("got %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSession_Subscribe(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName, err := createTable(1, numberP38S0, ses)
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	sub, err := ses.Subscribe(ora.SubscribeOptions{Rowids: true}, "SELECT * FROM "+tableName)
+	if err != nil {
+		t.Skipf("subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	_, err = ses.PrepAndExe(fmt.Sprintf("INSERT INTO %s (c1) VALUES (1)", tableName))
+	testErr(err, t)
+	_, err = ses.PrepAndExe("COMMIT")
+	testErr(err, t)
+
+	select {
+	case event := <-sub.Events():
+		if event.Type != ora.EventObject || len(event.Tables) != 1 {
+			t.Fatalf("got %#v", event)
+		}
+		tc := event.Tables[0]
+		if !strings.HasSuffix(tc.Name, "."+strings.ToUpper(tableName)) || tc.Operation&ora.OpInsert == 0 {
+			t.Errorf("got %#v", tc)
+		}
+	case <-time.After(10 * time.Second):
+		t.Skip("no notification arrived (is the client reachable from the server?)")
+	}
+
+	testErr(sub.Close(), t)
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Events is not closed")
+	}
+}
+
+func TestSession_DirectPathLoader(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf("CREATE TABLE %s (id NUMBER(9) NOT NULL, name VARCHAR2(10), born DATE)", tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	dp, err := ses.DirectPathLoader(tableName, []string{"id", "name", "born"}, ora.DirectPathOptions{NoLogging: true})
+	testErr(err, t)
+	born := time.Date(2017, 3, 4, 5, 6, 7, 0, time.Local)
+	testErr(dp.Load(
+		[]interface{}{1, "first", born},
+		[]interface{}{2, ora.String{IsNull: true}, nil},
+		[]interface{}{3, "third", struct{}{}}, // rejected
+	), t)
+	testErr(dp.LoadColumns(
+		[]int64{4, 5},
+		[]string{"fourth", "fifth"},
+		[]ora.Time{{Value: born}, {IsNull: true}},
+	), t)
+	// more rows than the column array, with a rejected row in a later batch
+	const n = 10000
+	ids, names, borns := make([]int64, n), make([]interface{}, n), make([]interface{}, n)
+	for i := range ids {
+		ids[i], names[i] = int64(100+i), fmt.Sprintf("n%d", i)
+	}
+	names[n-2] = struct{}{} // rejected
+	testErr(dp.LoadColumns(ids, names, borns), t)
+	res, err := dp.Finish()
+	testErr(err, t)
+	if res.Loaded != 4+n-1 || len(res.Rejected) != 2 || res.Rejected[0].Row != 2 || res.Rejected[1].Row != 5+n-2 {
+		t.Errorf("got %d loaded, %v rejected", res.Loaded, res.Rejected)
+	}
+
+	rset, err := ses.PrepAndQry(fmt.Sprintf("SELECT COUNT(0), COUNT(name), COUNT(born) FROM %s", tableName))
+	testErr(err, t)
+	row := rset.NextRow()
+	want := fmt.Sprintf("%d %d 2", 4+n-1, 3+n-1)
+	if got := fmt.Sprint(row...); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestSession_CopyIn(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf("CREATE TABLE %s (id NUMBER(9) PRIMARY KEY, name VARCHAR2(5))", tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	res, err := ses.CopyIn(tableName, []string{"id", "name"}, ora.CopyFromRows([][]interface{}{
+		{1, "a"},
+		{2, nil},
+		{1, "dup"},      // unique constraint violated
+		{3, "too long"}, // value too large
+		{4, struct{}{}}, // unsupported type
+		{int64(5), ora.String{Value: "e"}},
+	}), ora.CopyOptions{})
+	testErr(err, t)
+	if res.Rows != 3 || len(res.Errors) != 3 {
+		t.Fatalf("got %d rows, errors %v", res.Rows, res.Errors)
+	}
+	for i, want := range []int64{2, 3, 4} {
+		if res.Errors[i].Row != want {
+			t.Errorf("%d. got row %d, wanted %d (%v)", i, res.Errors[i].Row, want, res.Errors[i].Err)
+		}
+	}
+	if oe, ok := res.Errors[0].Err.(*ora.ORAError); !ok || oe.Code() != 1 {
+		t.Errorf("got %v, wanted unique violation", res.Errors[0].Err)
+	}
+
+	res, err = ses.CopyIn(tableName, []string{"id", "name"}, ora.CopyFromRows([][]interface{}{
+		{1, "z"},
+		{6, "f"},
+		{float64(7), "g"}, // ints and floats mixed in a column
+	}), ora.CopyOptions{Keys: []string{"id"}})
+	testErr(err, t)
+	if res.Rows != 3 || len(res.Errors) != 0 {
+		t.Fatalf("got %d rows, errors %v", res.Rows, res.Errors)
+	}
+	rset, err := ses.PrepAndQry(fmt.Sprintf("SELECT COUNT(0), MAX(name) FROM %s", tableName))
+	testErr(err, t)
+	row := rset.NextRow()
+	if got := fmt.Sprintf("%v %v", row...); got != "5 z" {
+		t.Errorf("got %q, wanted %q", got, "5 z")
+	}
+
+	// a failing batch of a single row
+	res, err = ses.CopyIn(tableName, []string{"id", "name"}, ora.CopyFromRows([][]interface{}{
+		{1, "dup"},
+	}), ora.CopyOptions{})
+	testErr(err, t)
+	if res.Rows != 0 || len(res.Errors) != 1 || res.Errors[0].Row != 0 {
+		t.Fatalf("single row: got %d rows, errors %v", res.Rows, res.Errors)
+	}
+
+	// a failing batch, followed by a batch of a single row
+	res, err = ses.CopyIn(tableName, []string{"id", "name"}, ora.CopyFromRows([][]interface{}{
+		{1, "dup"},
+		{8, "h"},
+		{9, "i"},
+	}), ora.CopyOptions{BatchSize: 2})
+	testErr(err, t)
+	if res.Rows != 2 || len(res.Errors) != 1 || res.Errors[0].Row != 0 {
+		t.Fatalf("last single row: got %d rows, errors %v", res.Rows, res.Errors)
+	}
+}
+
+func TestSession_Flashback(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf("CREATE TABLE %s (id NUMBER(9) PRIMARY KEY, name VARCHAR2(5))", tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+	_, err = ses.PrepAndExe(fmt.Sprintf("INSERT INTO %s (id, name) VALUES (1, 'a')", tableName))
+	testErr(err, t)
+
+	snap, err := ses.Snapshot()
+	if err != nil {
+		t.Skipf("no flashback: %v", err)
+	}
+	_, err = ses.PrepAndExe(fmt.Sprintf("UPDATE %s SET name = 'b' WHERE id = 1", tableName))
+	testErr(err, t)
+
+	name := func(ctx context.Context, qry string, snapshot ora.Snapshot) string {
+		stmt, err := ses.Prep(qry)
+		testErr(err, t)
+		defer stmt.Close()
+		stmt.SetCfg(stmt.Cfg().SetSnapshot(snapshot))
+		rset, err := stmt.QryContext(ctx)
+		testErr(err, t)
+		row := rset.NextRow()
+		testErr(rset.Err(), t)
+		if len(row) == 0 {
+			t.Fatalf("no row from %q", qry)
+		}
+		return row[0].(string)
+	}
+	qry := fmt.Sprintf("SELECT name FROM %s WHERE id = 1", tableName)
+	for i, tc := range []struct {
+		ctx      context.Context
+		qry      string
+		snapshot ora.Snapshot
+		await    string
+	}{
+		{context.Background(), qry, ora.Snapshot{}, "b"},
+		{snap.Context(context.Background()), qry, ora.Snapshot{}, "a"},
+		{context.Background(), qry, snap, "a"},
+		{context.Background(), fmt.Sprintf("SELECT name FROM %s WHERE id = 1", snap.Table(tableName)), ora.Snapshot{}, "a"},
+	} {
+		if got := name(tc.ctx, tc.qry, tc.snapshot); got != tc.await {
+			t.Errorf("%d. got %q, wanted %q", i, got, tc.await)
+		}
+	}
+
+	versions, err := ses.FlashbackVersions(tableName, map[string]interface{}{"id": 1}, snap, ora.Snapshot{})
+	testErr(err, t)
+	if len(versions) == 0 {
+		t.Fatal("no versions")
+	}
+	last := versions[len(versions)-1]
+	if last.Operation != "U" || last.Row["NAME"] != "b" || last.StartSCN <= snap.SCN {
+		t.Errorf("got %+v, wanted the update after %d", last, snap.SCN)
+	}
+}
+
+func TestSession_Describe(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf(`CREATE TABLE %s (id NUMBER(9) NOT NULL, "with space" VARCHAR2(10), born DATE)`, tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	cols, err := ses.DescribeQuery(fmt.Sprintf(`SELECT id, "with space", born FROM %s`, tableName))
+	testErr(err, t)
+	if len(cols) != 3 || cols[1].Name != "with space" || cols[0].Nullable || !cols[1].Nullable {
+		t.Errorf("got %#v", cols)
+	}
+
+	obj, err := ses.Describe(tableName)
+	testErr(err, t)
+	if obj.Type != ora.ObjTable || len(obj.Columns) != 3 || obj.Columns[0].Precision != 9 || obj.Columns[1].Name != "with space" {
+		t.Errorf("got %#v", obj)
+	}
+
+	obj, err = ses.Describe("SYS.DBMS_OUTPUT")
+	testErr(err, t)
+	if obj.Type != ora.ObjPackage {
+		t.Fatalf("got %s, wanted %s", obj.Type, ora.ObjPackage)
+	}
+	var found bool
+	for _, sub := range obj.Subprograms {
+		if sub.Name == "GET_LINE" {
+			found = !sub.Function && len(sub.Arguments) == 2 &&
+				sub.Arguments[0].Name == "LINE" && sub.Arguments[0].Direction == ora.ArgOut
+		}
+	}
+	if !found {
+		t.Errorf("GET_LINE(line OUT, status OUT) not found in %#v", obj.Subprograms)
+	}
+}
+
+func TestSession_Columns(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf(`CREATE TABLE %s (id NUMBER(5,2) NOT NULL, name VARCHAR2(10 CHAR), nname NVARCHAR2(10), ts TIMESTAMP WITH TIME ZONE, data BLOB)`, tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+
+	rset, err := ses.PrepAndQry(fmt.Sprintf(`SELECT id, name, nname, ts, data FROM %s`, tableName))
+	testErr(err, t)
+	defer rset.Exhaust()
+	cols := rset.Columns
+	if len(cols) != 5 {
+		t.Fatalf("got %d columns, wanted 5", len(cols))
+	}
+	for i, want := range []struct {
+		typ      ora.OracleType
+		nullable bool
+		gct      ora.GoColumnType
+	}{
+		{ora.TypeNumber, false, ora.F64},
+		{ora.TypeVarchar2, true, ora.S},
+		{ora.TypeVarchar2, true, ora.S},
+		{ora.TypeTimestampTZ, true, ora.T},
+		{ora.TypeBlob, true, ora.Bin},
+	} {
+		c := cols[i]
+		if c.Type != want.typ || c.Nullable != want.nullable || c.GoType != want.gct {
+			t.Errorf("%d. got %s nullable=%t %s, wanted %s nullable=%t %s", i, c.Type, c.Nullable, ora.GctName(c.GoType), want.typ, want.nullable, ora.GctName(want.gct))
+		}
+	}
+	if cols[0].Precision != 5 || cols[0].Scale != 2 {
+		t.Errorf("got NUMBER(%d,%d), wanted NUMBER(5,2)", cols[0].Precision, cols[0].Scale)
+	}
+	if !cols[1].CharUsed || cols[1].CharLength != 10 || cols[1].CharsetForm != ora.CharsetFormImplicit {
+		t.Errorf("got %#v, wanted VARCHAR2(10 CHAR)", cols[1])
+	}
+	if cols[2].CharsetForm != ora.CharsetFormNChar {
+		t.Errorf("got charset form %d, wanted %d", cols[2].CharsetForm, ora.CharsetFormNChar)
+	}
+}
+
+func TestRset_ScanStruct(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	tableName := tableName()
+	_, err = ses.PrepAndExe(fmt.Sprintf(`CREATE TABLE %s (id NUMBER(9) NOT NULL, name VARCHAR2(10), amount NUMBER(9,2))`, tableName))
+	testErr(err, t)
+	defer dropTable(tableName, ses, t)
+	_, err = ses.PrepAndExe(fmt.Sprintf(`INSERT INTO %s SELECT LEVEL, 'n'||LEVEL, DECODE(LEVEL, 2, NULL, LEVEL/2) FROM DUAL CONNECT BY LEVEL <= 3`, tableName))
+	testErr(err, t)
+	qry := fmt.Sprintf(`SELECT id, name, amount FROM %s ORDER BY id`, tableName)
+
+	type row struct {
+		ID     int64
+		Name   string      `db:"name"`
+		Amount ora.Float64 `db:"amount"`
+		Other  string      `db:"-"`
+	}
+	rset, err := ses.PrepAndQry(qry)
+	testErr(err, t)
+	var (
+		id   int
+		name sql.NullString
+		amt  *float64
+	)
+	if !rset.Next() {
+		t.Fatal(rset.Err())
+	}
+	testErr(rset.Scan(&id, &name, &amt), t)
+	if id != 1 || name.String != "n1" || amt == nil || *amt != 0.5 {
+		t.Errorf("got %d, %v, %v", id, name, amt)
+	}
+	var r row
+	if !rset.Next() {
+		t.Fatal(rset.Err())
+	}
+	testErr(rset.ScanStruct(&r), t)
+	if r.ID != 2 || r.Name != "n2" || !r.Amount.IsNull {
+		t.Errorf("got %#v", r)
+	}
+	rset.Exhaust()
+
+	stmt, err := ses.Prep(qry)
+	testErr(err, t)
+	defer stmt.Close()
+	rset, err = stmt.Qry()
+	testErr(err, t)
+	var rows []*row
+	testErr(rset.All(&rows), t)
+	if len(rows) != 3 || rows[2].ID != 3 || rows[2].Amount.Value != 1.5 {
+		t.Errorf("got %#v", rows)
+	}
+	if gct := rset.Columns[2].GoType; gct != ora.OraF64 {
+		t.Errorf("amount fetched as %s, wanted OraF64", ora.GctName(gct))
+	}
+}
+
 func TestSession_PrepAndExe(t *testing.T) {
 	t.Parallel()
 	ses, err := testSesPool.Get()
@@ -266,6 +816,61 @@ func TestSession_PrepAndQry(t *testing.T) {
 	}
 }
 
+func TestSession_PrepAndQryContext(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	rset, err := ses.PrepAndQryContext(ctx,
+		"SELECT COUNT(0) FROM all_objects A, all_objects B, all_objects C")
+	if err == nil {
+		for rset.NextContext(ctx) {
+		}
+		err = rset.Err()
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("cancel took %s", d)
+	}
+
+	// the session must be usable after the break
+	testErr(ses.PingContext(context.Background()), t)
+}
+
+func TestSession_CallTimeout(t *testing.T) {
+	t.Parallel()
+	ses, err := testSesPool.Get()
+	testErr(err, t)
+	defer ses.Close()
+
+	stmt, err := ses.Prep("SELECT COUNT(0) FROM all_objects A, all_objects B, all_objects C")
+	testErr(err, t)
+	defer stmt.Close()
+	stmt.SetCfg(stmt.Cfg().SetCallTimeout(time.Second))
+	start := time.Now()
+	rset, err := stmt.Qry()
+	if err == nil {
+		for rset.Next() {
+		}
+		err = rset.Err()
+	}
+	if !ora.IsCallTimeout(err) {
+		t.Errorf("expected call timeout, got %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("timeout took %s", d)
+	}
+
+	// the session must be usable after the timeout
+	testErr(ses.Ping(), t)
+}
+
 var _cgocheck int = 1
 
 func cgocheck() int {